@@ -6,16 +6,27 @@ import (
 )
 
 type ClusterConfigSpec struct {
+	// The AuthenticationClasses of the cluster. Only one login provider class is supported: NiFi uses a single
+	// login identity provider, so one LDAP, static, Kerberos or OIDC class, and OIDC replaces the login identity
+	// provider. Login providers are not chained, e.g. LDAP with a static `admin` login is rejected.
+	// A TLS class can be combined with any class and is the break-glass path: a client certificate signed by
+	// the server SecretClass of the cluster authenticates its common name when the login provider is unavailable.
 	// +kubebuilder:validation:Optional
 	Authentication []AuthenticationSpec `json:"authentication,omitempty"`
 
+	// +kubebuilder:validation:Optional
+	Authorization *AuthorizationSpec `json:"authorization,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	// +default:value={"enable": true}
 	CreateReportingTaskJob *CreateReportingTaskJobSpec `json:"createReportingTaskJob,omitempty"`
 
-	// +kubebuilder:validation:Type=object
-	// +kubebuilder:default={}
+	// Extra Pod volumes, each one is mounted into the NiFi containers at `/kubedoop/userdata/<volume name>`,
+	// e.g. to provide keytabs, JDBC drivers or certificates to processors.
 	// Ref Pod spec.Volumes: https://kubernetes.io/docs/reference/kubernetes-api/workload-resources/pod-v1/#podspecvolumes
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
 	ExtraVolumes *k8sruntime.RawExtension `json:"extraVolumes,omitempty"`
 
 	// +kubebuilder:validation:Required
@@ -28,7 +39,11 @@ type ClusterConfigSpec struct {
 	// +kubebuilder:validation:Optional
 	Tls *TlsSpec `json:"tls,omitempty"`
 
+	// ListenerClass sets the type of the Service exposing the NiFi UI and REST API:
+	// `cluster-internal` (ClusterIP), `external-unstable` (NodePort) or `external-stable` (LoadBalancer).
 	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="cluster-internal"
+	// +kubebuilder:validation:Enum=cluster-internal;external-unstable;external-stable
 	ListenerClass string `json:"listenerClass,omitempty"`
 
 	// +kubebuilder:validation:Optional
@@ -77,11 +92,124 @@ type GitSyncSpec struct {
 
 // AuthenticationSpec defines the authentication spec.
 type AuthenticationSpec struct {
+	// The AuthenticationClass of the users. NiFi always verifies the TLS servers it connects to, an LDAP or OIDC
+	// class with TLS must verify the server with a CA SecretClass or webPki, `verification: none` is rejected.
 	// +kubebuilder:validation:Required
 	AuthenticationClass string `json:"authenticationClass"`
 
+	// The identity of the user granted the admin policies on the first start, as NiFi identifies the users of
+	// the class: the DN of an LDAP user, e.g. `uid=alice,ou=users,dc=example,dc=org`, the principal of a
+	// Kerberos user, e.g. `alice@EXAMPLE.COM`, the principal claim of an OIDC user, or the common name of a TLS
+	// client certificate, e.g. `alice` for `CN=alice`. Not supported with a static AuthenticationClass, its
+	// `admin` user is the initial admin and no other class of the cluster may set initialAdmin.
+	// Required unless a static AuthenticationClass, LDAP bind credentials or OIDC admin groups are used. With LDAP
+	// bind credentials it defaults to the DN of the bind user, which is not recommended as the bind account then
+	// administers NiFi. The operator has no credentials of this user and does not query the NiFi REST API as it.
+	// +kubebuilder:validation:Optional
+	InitialAdmin string `json:"initialAdmin,omitempty"`
+
+	// The OIDC client of the cluster, only used with an OIDC AuthenticationClass.
+	// NiFi verifies a provider with TLS against the CA of a secret-operator SecretClass, merged into its truststore,
+	// or against the webPki CAs of the JDK. A CA from a ConfigMap is not supported, issue it with a SecretClass.
 	// +kubebuilder:validation:Optional
 	Oidc *authenticationv1alpha1.OidcSpec `json:"oidc,omitempty"`
+
+	// The groups of the OIDC users and the policies granted to them, only used with an OIDC AuthenticationClass.
+	// The groups and policies are only seeded on the first start, while the database repository holds no users
+	// and policies yet. Later changes of the groups are not applied, manage the policies in NiFi then.
+	// +kubebuilder:validation:Optional
+	OidcGroups *OidcGroupsSpec `json:"oidcGroups,omitempty"`
+
+	// The search of the groups of the LDAP users, only used with an LDAP AuthenticationClass.
+	// When set, NiFi syncs the LDAP users and groups, otherwise the users and policies are managed in NiFi.
+	// +kubebuilder:validation:Optional
+	LdapGroups *LdapGroupsSpec `json:"ldapGroups,omitempty"`
+
+	// Negotiate TLS with START_TLS on the plain LDAP port, 389 by default, instead of connecting with LDAP over TLS,
+	// 636 by default. Only used with an LDAP AuthenticationClass with TLS.
+	// +kubebuilder:validation:Optional
+	LdapStartTls bool `json:"ldapStartTls,omitempty"`
+}
+
+// LdapGroupsSpec configures how NiFi reads the groups of the LDAP users, the users and groups are synced periodically.
+type LdapGroupsSpec struct {
+	// The base DN of the group search, e.g. `ou=groups,dc=example,dc=org`.
+	// Without it, the groups are read from the group attribute of the users, `memberof` by default.
+	// +kubebuilder:validation:Optional
+	SearchBase string `json:"searchBase,omitempty"`
+
+	// The object class of the groups.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="groupOfNames"
+	ObjectClass string `json:"objectClass,omitempty"`
+
+	// The attribute of a group listing the DNs of its members.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="member"
+	MemberAttribute string `json:"memberAttribute,omitempty"`
+
+	// How often the users and groups are synced, a go duration string such as "30m".
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="30m"
+	SyncInterval string `json:"syncInterval,omitempty"`
+}
+
+// OidcGroupsSpec reads the groups of the OIDC users from a claim and grants a policy set to each listed group.
+// NiFi matches the groups of the claim to its groups by name.
+type OidcGroupsSpec struct {
+	// The claim of the ID token holding the groups of the user.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="groups"
+	Claim string `json:"claim,omitempty"`
+
+	// Groups allowed to manage the flow, the controller, the users and the policies.
+	// +kubebuilder:validation:Optional
+	Admin []string `json:"admin,omitempty"`
+
+	// Groups allowed to view the flow and the controller, read provenance and reset counters.
+	// +kubebuilder:validation:Optional
+	Operator []string `json:"operator,omitempty"`
+
+	// Groups allowed to view the flow.
+	// +kubebuilder:validation:Optional
+	ReadOnly []string `json:"readOnly,omitempty"`
+}
+
+// AuthorizationSpec defines how NiFi authorizes the requests of authenticated users.
+// Without it, the users and policies are managed in NiFi.
+type AuthorizationSpec struct {
+	// +kubebuilder:validation:Optional
+	Opa *OpaSpec `json:"opa,omitempty"`
+}
+
+// OpaSpec delegates the authorization decisions to Open Policy Agent.
+// The NiFi image must contain the nifi-opa-plugin (https://github.com/DavidGitter/nifi-opa-plugin).
+type OpaSpec struct {
+	// The discovery ConfigMap of the OPA cluster, its `OPA` key contains the OPA base URL.
+	// +kubebuilder:validation:Required
+	ConfigMapName string `json:"configMapName"`
+
+	// The Rego package containing the `allow` rule NiFi queries.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="nifi"
+	Package string `json:"package,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Cache *OpaCacheSpec `json:"cache,omitempty"`
+}
+
+// OpaCacheSpec configures the cache of the OPA decisions in NiFi.
+type OpaCacheSpec struct {
+	// How long a decision is cached, a go duration string such as "30s" or "1m".
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="30s"
+	EntryTimeToLive string `json:"entryTimeToLive,omitempty"`
+
+	// The maximum number of cached decisions.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=10000
+	// +kubebuilder:validation:Minimum=1
+	MaxEntries int32 `json:"maxEntries,omitempty"`
 }
 
 type CreateReportingTaskJobSpec struct {
@@ -124,5 +252,14 @@ type SensitivePropertiesSpec struct {
 }
 
 type TlsSpec struct {
+	// The SecretClass issuing the certificate of the web connector, the cluster protocol and load balancing.
+	// NiFi builds one SSL context from `nifi.security.keystore` for all of them,
+	// node-to-node traffic cannot use a separate cluster-internal certificate.
 	ServerSecretClass string `json:"serverSecretClass"`
+
+	// The lifetime requested for the server certificate, a Go duration of at least 1h and at least 20m per node, e.g. `24h`.
+	// Defaults to the lifetime of the SecretClass. The nodes are restarted one at a time, starting at 80% of the lifetime
+	// or 10m per node before the certificates expire, whichever is earlier.
+	// +kubebuilder:validation:Optional
+	CertLifetime string `json:"certLifetime,omitempty"`
 }
@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -67,6 +68,57 @@ type RoleGroupSpec struct {
 // ConfigSpec defines the config spec.
 type ConfigSpec struct {
 	*commonsv1alpha1.RoleGroupConfigSpec `json:",inline"`
+
+	// Storage of the NiFi repositories. Every repository is backed by its own
+	// PersistentVolumeClaim, repositories that are not set use the operator defaults.
+	// Changing the claims recreates the StatefulSet, the nodes restart one at a time and existing claims are not resized.
+	// +kubebuilder:validation:Optional
+	Storage *StorageSpec `json:"storage,omitempty"`
+}
+
+// StorageSpec defines the storage of the NiFi repositories.
+type StorageSpec struct {
+	// +kubebuilder:validation:Optional
+	ContentRepo *RepositoryStorageSpec `json:"contentRepo,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	DatabaseRepo *RepositoryStorageSpec `json:"databaseRepo,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	FlowfileRepo *RepositoryStorageSpec `json:"flowfileRepo,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	ProvenanceRepo *RepositoryStorageSpec `json:"provenanceRepo,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	StateRepo *RepositoryStorageSpec `json:"stateRepo,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	StatusRepo *RepositoryStorageSpec `json:"statusRepo,omitempty"`
+
+	// Additional content repository directories keyed by name. Each one is backed by its own
+	// PersistentVolumeClaim and registered as `nifi.content.repository.directory.<name>`,
+	// so NiFi stripes content claims across all of them.
+	// The name must be a DNS-1123 label and `default` is reserved.
+	// +kubebuilder:validation:Optional
+	ContentRepoVolumes map[string]RepositoryStorageSpec `json:"contentRepoVolumes,omitempty"`
+
+	// Additional provenance repository directories keyed by name. Each one is backed by its own
+	// PersistentVolumeClaim and registered as `nifi.provenance.repository.directory.<name>`.
+	// The name must be a DNS-1123 label and `default` is reserved.
+	// +kubebuilder:validation:Optional
+	ProvenanceRepoVolumes map[string]RepositoryStorageSpec `json:"provenanceRepoVolumes,omitempty"`
+}
+
+// RepositoryStorageSpec defines the PersistentVolumeClaim of a NiFi repository.
+type RepositoryStorageSpec struct {
+	// The capacity of the PersistentVolumeClaims. A larger capacity expands the existing claims,
+	// the StorageClass must allow volume expansion. The claims are never shrunk.
+	// +kubebuilder:validation:Optional
+	Capacity *resource.Quantity `json:"capacity,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	StorageClass string `json:"storageClass,omitempty"`
 }
 
 type JVMArgumentOverridesSpec struct {
@@ -77,11 +129,69 @@ type JVMArgumentOverridesSpec struct {
 
 // NifiClusterStatus defines the observed state of NifiCluster.
 type NifiClusterStatus struct {
+	// ObservedGeneration is the most recent generation reconciled by the operator.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions are Available, Progressing, Degraded and ReconciliationPaused.
+	// +kubebuilder:validation:Optional
+	// +listType=map
+	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Nodes is the cluster membership reported by the NiFi REST API (`/nifi-api/controller/cluster`).
+	// Kubernetes readiness does not imply that a node is connected to the NiFi cluster.
+	// With TLS the operator queries the REST API as the `admin` user of a static AuthenticationClass, it has no
+	// credentials of an LDAP, Kerberos, OIDC or TLS user, so the membership is not reported without one.
+	// +kubebuilder:validation:Optional
+	Nodes []NodeStatus `json:"nodes,omitempty"`
+
+	// PrimaryNode is the address of the node elected as primary node.
+	// +kubebuilder:validation:Optional
+	PrimaryNode string `json:"primaryNode,omitempty"`
+
+	// ClusterCoordinator is the address of the node elected as cluster coordinator.
+	// +kubebuilder:validation:Optional
+	ClusterCoordinator string `json:"clusterCoordinator,omitempty"`
+
+	// NextCertificateRotation is when the next node is restarted to renew its expiring TLS certificate.
+	// It is empty without TLS and when the secret-operator recorded no certificate expiry on the node pods,
+	// the operator logs the pods without an expiry in the latter case.
+	// +kubebuilder:validation:Optional
+	NextCertificateRotation *metav1.Time `json:"nextCertificateRotation,omitempty"`
+}
+
+// NodeStatus is the state of a NiFi node as reported by the cluster coordinator.
+type NodeStatus struct {
+	// +kubebuilder:validation:Required
+	NodeID string `json:"nodeId"`
+
+	// +kubebuilder:validation:Required
+	Address string `json:"address"`
+
+	// +kubebuilder:validation:Optional
+	APIPort int32 `json:"apiPort,omitempty"`
+
+	// Connection state of the node, e.g. CONNECTED, CONNECTING, DISCONNECTED, OFFLOADED.
+	// +kubebuilder:validation:Required
+	Status string `json:"status"`
+
+	// Roles held by the node, e.g. `Primary Node`, `Cluster Coordinator`.
+	// +kubebuilder:validation:Optional
+	Roles []string `json:"roles,omitempty"`
+
+	// Number of threads the node was running when the cluster membership was last refreshed.
+	// +kubebuilder:validation:Optional
+	ActiveThreadCount int32 `json:"activeThreadCount,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Available",type="string",JSONPath=".status.conditions[?(@.type==\"Available\")].status"
+// +kubebuilder:printcolumn:name="Progressing",type="string",JSONPath=".status.conditions[?(@.type==\"Progressing\")].status"
+// +kubebuilder:printcolumn:name="Degraded",type="string",JSONPath=".status.conditions[?(@.type==\"Degraded\")].status"
+// +kubebuilder:printcolumn:name="Paused",type="string",JSONPath=".status.conditions[?(@.type==\"ReconciliationPaused\")].status",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // NifiCluster is the Schema for the nificlusters API.
 type NifiCluster struct {
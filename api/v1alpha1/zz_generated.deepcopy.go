@@ -36,6 +36,16 @@ func (in *AuthenticationSpec) DeepCopyInto(out *AuthenticationSpec) {
 		*out = new(authenticationv1alpha1.OidcSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.OidcGroups != nil {
+		in, out := &in.OidcGroups, &out.OidcGroups
+		*out = new(OidcGroupsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LdapGroups != nil {
+		in, out := &in.LdapGroups, &out.LdapGroups
+		*out = new(LdapGroupsSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationSpec.
@@ -48,6 +58,26 @@ func (in *AuthenticationSpec) DeepCopy() *AuthenticationSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthorizationSpec) DeepCopyInto(out *AuthorizationSpec) {
+	*out = *in
+	if in.Opa != nil {
+		in, out := &in.Opa, &out.Opa
+		*out = new(OpaSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthorizationSpec.
+func (in *AuthorizationSpec) DeepCopy() *AuthorizationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthorizationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterConfigSpec) DeepCopyInto(out *ClusterConfigSpec) {
 	*out = *in
@@ -58,6 +88,11 @@ func (in *ClusterConfigSpec) DeepCopyInto(out *ClusterConfigSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Authorization != nil {
+		in, out := &in.Authorization, &out.Authorization
+		*out = new(AuthorizationSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.CreateReportingTaskJob != nil {
 		in, out := &in.CreateReportingTaskJob, &out.CreateReportingTaskJob
 		*out = new(CreateReportingTaskJobSpec)
@@ -110,6 +145,11 @@ func (in *ConfigSpec) DeepCopyInto(out *ConfigSpec) {
 		*out = new(commonsv1alpha1.RoleGroupConfigSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigSpec.
@@ -214,6 +254,21 @@ func (in *JVMArgumentOverridesSpec) DeepCopy() *JVMArgumentOverridesSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LdapGroupsSpec) DeepCopyInto(out *LdapGroupsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LdapGroupsSpec.
+func (in *LdapGroupsSpec) DeepCopy() *LdapGroupsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LdapGroupsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NifiCluster) DeepCopyInto(out *NifiCluster) {
 	*out = *in
@@ -318,6 +373,17 @@ func (in *NifiClusterStatus) DeepCopyInto(out *NifiClusterStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NextCertificateRotation != nil {
+		in, out := &in.NextCertificateRotation, &out.NextCertificateRotation
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NifiClusterStatus.
@@ -330,6 +396,26 @@ func (in *NifiClusterStatus) DeepCopy() *NifiClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeStatus.
+func (in *NodeStatus) DeepCopy() *NodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodesSpec) DeepCopyInto(out *NodesSpec) {
 	*out = *in
@@ -372,6 +458,91 @@ func (in *NodesSpec) DeepCopy() *NodesSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OidcGroupsSpec) DeepCopyInto(out *OidcGroupsSpec) {
+	*out = *in
+	if in.Admin != nil {
+		in, out := &in.Admin, &out.Admin
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Operator != nil {
+		in, out := &in.Operator, &out.Operator
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReadOnly != nil {
+		in, out := &in.ReadOnly, &out.ReadOnly
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OidcGroupsSpec.
+func (in *OidcGroupsSpec) DeepCopy() *OidcGroupsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OidcGroupsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpaCacheSpec) DeepCopyInto(out *OpaCacheSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpaCacheSpec.
+func (in *OpaCacheSpec) DeepCopy() *OpaCacheSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OpaCacheSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpaSpec) DeepCopyInto(out *OpaSpec) {
+	*out = *in
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(OpaCacheSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpaSpec.
+func (in *OpaSpec) DeepCopy() *OpaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OpaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryStorageSpec) DeepCopyInto(out *RepositoryStorageSpec) {
+	*out = *in
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryStorageSpec.
+func (in *RepositoryStorageSpec) DeepCopy() *RepositoryStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RoleGroupSpec) DeepCopyInto(out *RoleGroupSpec) {
 	*out = *in
@@ -422,6 +593,65 @@ func (in *SensitivePropertiesSpec) DeepCopy() *SensitivePropertiesSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
+	*out = *in
+	if in.ContentRepo != nil {
+		in, out := &in.ContentRepo, &out.ContentRepo
+		*out = new(RepositoryStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DatabaseRepo != nil {
+		in, out := &in.DatabaseRepo, &out.DatabaseRepo
+		*out = new(RepositoryStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FlowfileRepo != nil {
+		in, out := &in.FlowfileRepo, &out.FlowfileRepo
+		*out = new(RepositoryStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProvenanceRepo != nil {
+		in, out := &in.ProvenanceRepo, &out.ProvenanceRepo
+		*out = new(RepositoryStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StateRepo != nil {
+		in, out := &in.StateRepo, &out.StateRepo
+		*out = new(RepositoryStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StatusRepo != nil {
+		in, out := &in.StatusRepo, &out.StatusRepo
+		*out = new(RepositoryStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContentRepoVolumes != nil {
+		in, out := &in.ContentRepoVolumes, &out.ContentRepoVolumes
+		*out = make(map[string]RepositoryStorageSpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.ProvenanceRepoVolumes != nil {
+		in, out := &in.ProvenanceRepoVolumes, &out.ProvenanceRepoVolumes
+		*out = make(map[string]RepositoryStorageSpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSpec.
+func (in *StorageSpec) DeepCopy() *StorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TlsSpec) DeepCopyInto(out *TlsSpec) {
 	*out = *in
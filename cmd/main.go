@@ -41,6 +41,7 @@ import (
 	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
 	"github.com/zncdatadev/nifi-operator/internal/controller"
 	"github.com/zncdatadev/nifi-operator/internal/version"
+	webhookv1alpha1 "github.com/zncdatadev/nifi-operator/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -222,6 +223,14 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "NifiCluster")
 		os.Exit(1)
 	}
+	// The validating webhook needs serving certificates, see config/default/manager_webhook_patch.yaml.
+	// It is opt-in so the operator keeps running in deployments without webhook certificates.
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		if err = webhookv1alpha1.SetupNifiClusterWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "NifiCluster")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
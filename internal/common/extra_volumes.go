@@ -0,0 +1,61 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ExtraVolumesMountDir is the directory the extra volumes are mounted in, each one at `<dir>/<volume name>`.
+const ExtraVolumesMountDir = "/kubedoop/userdata"
+
+// DecodeExtraVolumes decodes `clusterConfig.extraVolumes`, a list of Pod volumes.
+// An empty value, `null` and the former default `{}` mean no extra volumes.
+func DecodeExtraVolumes(raw *k8sruntime.RawExtension) ([]corev1.Volume, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	data := bytes.TrimSpace(raw.Raw)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) || bytes.Equal(data, []byte("{}")) {
+		return nil, nil
+	}
+	if data[0] != '[' {
+		return nil, fmt.Errorf("extraVolumes must be a list of Pod volumes")
+	}
+
+	var volumes []corev1.Volume
+	if err := json.Unmarshal(data, &volumes); err != nil {
+		return nil, fmt.Errorf("failed to decode extraVolumes: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(volumes))
+	for i, volume := range volumes {
+		if msgs := validation.IsDNS1123Label(volume.Name); len(msgs) > 0 {
+			return nil, fmt.Errorf("extraVolumes[%d]: invalid name %q: %v", i, volume.Name, msgs)
+		}
+		if _, exists := names[volume.Name]; exists {
+			return nil, fmt.Errorf("extraVolumes[%d]: duplicate name %q", i, volume.Name)
+		}
+		names[volume.Name] = struct{}{}
+	}
+
+	return volumes, nil
+}
+
+// GetExtraVolumeMounts mounts every extra volume at `/kubedoop/userdata/<volume name>`.
+func GetExtraVolumeMounts(volumes []corev1.Volume) []corev1.VolumeMount {
+	mounts := make([]corev1.VolumeMount, 0, len(volumes))
+	for _, volume := range volumes {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: path.Join(ExtraVolumesMountDir, volume.Name),
+		})
+	}
+	return mounts
+}
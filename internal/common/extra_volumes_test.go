@@ -0,0 +1,65 @@
+package common
+
+import (
+	"testing"
+
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDecodeExtraVolumes_Empty(t *testing.T) {
+	for _, raw := range []*k8sruntime.RawExtension{nil, {}, {Raw: []byte("null")}, {Raw: []byte(" {} ")}, {Raw: []byte("[]")}} {
+		volumes, err := DecodeExtraVolumes(raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(volumes) != 0 {
+			t.Errorf("expected no volumes, got %d", len(volumes))
+		}
+	}
+}
+
+func TestDecodeExtraVolumes(t *testing.T) {
+	raw := &k8sruntime.RawExtension{Raw: []byte(`[
+		{"name": "keytab", "secret": {"secretName": "nifi-keytab"}},
+		{"name": "jdbc-drivers", "configMap": {"name": "jdbc-drivers"}}
+	]`)}
+
+	volumes, err := DecodeExtraVolumes(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(volumes) != 2 {
+		t.Fatalf("expected 2 volumes, got %d", len(volumes))
+	}
+	if volumes[0].Secret == nil || volumes[0].Secret.SecretName != "nifi-keytab" {
+		t.Errorf("expected the keytab secret volume, got %+v", volumes[0])
+	}
+
+	mounts := GetExtraVolumeMounts(volumes)
+	expected := map[string]string{
+		"keytab":       "/kubedoop/userdata/keytab",
+		"jdbc-drivers": "/kubedoop/userdata/jdbc-drivers",
+	}
+	for _, mount := range mounts {
+		if mount.MountPath != expected[mount.Name] {
+			t.Errorf("%s: expected mount path %s, got %s", mount.Name, expected[mount.Name], mount.MountPath)
+		}
+	}
+}
+
+func TestDecodeExtraVolumes_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"not a list":     `{"name": "keytab"}`,
+		"invalid name":   `[{"name": "Keytab_1", "emptyDir": {}}]`,
+		"duplicate name": `[{"name": "keytab", "emptyDir": {}}, {"name": "keytab", "emptyDir": {}}]`,
+		"malformed":      `[{"name": 1}]`,
+	}
+
+	for name, raw := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeExtraVolumes(&k8sruntime.RawExtension{Raw: []byte(raw)}); err == nil {
+				t.Errorf("expected an error for %s", raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package nifiapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	accessTokenPath       = "/nifi-api/access/token"
+	controllerClusterPath = "/nifi-api/controller/cluster"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// Node roles reported by NiFi in the cluster endpoint.
+const (
+	RolePrimaryNode        = "Primary Node"
+	RoleClusterCoordinator = "Cluster Coordinator"
+)
+
+// ClusterEntity is the response of `GET /nifi-api/controller/cluster`.
+type ClusterEntity struct {
+	Cluster Cluster `json:"cluster"`
+}
+
+type Cluster struct {
+	Nodes     []Node `json:"nodes"`
+	Generated string `json:"generated,omitempty"`
+}
+
+type Node struct {
+	NodeID            string   `json:"nodeId"`
+	Address           string   `json:"address"`
+	APIPort           int32    `json:"apiPort"`
+	Status            string   `json:"status"`
+	Roles             []string `json:"roles,omitempty"`
+	ActiveThreadCount int32    `json:"activeThreadCount"`
+}
+
+// HasRole reports whether the node currently holds the given cluster role.
+func (n *Node) HasRole(role string) bool {
+	return slices.Contains(n.Roles, role)
+}
+
+// Client is a minimal NiFi REST API client used by the operator to observe the cluster.
+//
+// When a username is set, the client logs in with `POST /nifi-api/access/token` and
+// sends the returned token as bearer token. NiFi only accepts logins over HTTPS.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	username   string
+	password   string
+}
+
+type Option func(*Client)
+
+// WithCredentials sets the username and password used to obtain an access token.
+func WithCredentials(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithCACert trusts the given PEM encoded CA certificates for HTTPS connections.
+func WithCACert(caPEM []byte) Option {
+	return func(c *Client) {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(caPEM)
+		c.httpClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:    pool,
+				MinVersion: tls.VersionTLS12,
+			},
+		}
+	}
+}
+
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GetCluster returns the cluster membership as seen by the node the client is connected to.
+func (c *Client) GetCluster(ctx context.Context) (*Cluster, error) {
+	token := ""
+	if c.username != "" {
+		var err error
+		if token, err = c.getAccessToken(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+controllerClusterPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := &ClusterEntity{}
+	if err := json.Unmarshal(body, entity); err != nil {
+		return nil, fmt.Errorf("failed to decode %s response: %w", controllerClusterPath, err)
+	}
+	return &entity.Cluster, nil
+}
+
+func (c *Client) getAccessToken(ctx context.Context) (string, error) {
+	form := url.Values{}
+	form.Set("username", c.username)
+	form.Set("password", c.password)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+accessTokenPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: failed to read response: %w", req.Method, req.URL.Path, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: unexpected status %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
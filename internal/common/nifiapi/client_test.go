@@ -0,0 +1,106 @@
+package nifiapi
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const clusterResponse = `{
+  "cluster": {
+    "nodes": [
+      {
+        "nodeId": "a1",
+        "address": "simple-nifi-node-default-0.simple-nifi-node-default.default.svc.cluster.local",
+        "apiPort": 9443,
+        "status": "CONNECTED",
+        "roles": ["Primary Node", "Cluster Coordinator"],
+        "activeThreadCount": 3,
+        "queued": "0 / 0 bytes"
+      },
+      {
+        "nodeId": "b2",
+        "address": "simple-nifi-node-default-1.simple-nifi-node-default.default.svc.cluster.local",
+        "apiPort": 9443,
+        "status": "CONNECTING",
+        "roles": [],
+        "activeThreadCount": 0
+      }
+    ],
+    "generated": "10:00:00 UTC"
+  }
+}`
+
+func TestGetCluster_WithCredentials(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case accessTokenPath:
+			if err := r.ParseForm(); err != nil {
+				t.Fatalf("failed to parse login form: %v", err)
+			}
+			if r.Form.Get("username") != "admin" || r.Form.Get("password") != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte("token-123"))
+		case controllerClusterPath:
+			if r.Header.Get("Authorization") != "Bearer token-123" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(clusterResponse))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	client := NewClient(server.URL, WithCACert(caPEM), WithCredentials("admin", "secret"))
+
+	cluster, err := client.GetCluster(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cluster.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(cluster.Nodes))
+	}
+	primary := cluster.Nodes[0]
+	if primary.Status != "CONNECTED" || primary.ActiveThreadCount != 3 || primary.APIPort != 9443 {
+		t.Errorf("unexpected node: %+v", primary)
+	}
+	if !primary.HasRole(RolePrimaryNode) || !primary.HasRole(RoleClusterCoordinator) {
+		t.Errorf("expected primary node and cluster coordinator roles, got %v", primary.Roles)
+	}
+	if cluster.Nodes[1].HasRole(RolePrimaryNode) {
+		t.Errorf("expected second node not to be primary")
+	}
+}
+
+func TestGetCluster_UntrustedCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(clusterResponse))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithCACert(nil))
+	if _, err := client.GetCluster(context.Background()); err == nil {
+		t.Fatal("expected an error for an untrusted server certificate")
+	}
+}
+
+func TestGetCluster_UnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte("node is disconnected"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if _, err := client.GetCluster(context.Background()); err == nil {
+		t.Fatal("expected an error for a non 2xx response")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 
 	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/client"
@@ -23,18 +24,27 @@ const (
 	AuthenticatorTypeLDAP AuthenticatorType = "ldap"
 	AuthenticatorTypeOIDC AuthenticatorType = "oidc"
 	AuthenticatorStatic   AuthenticatorType = "static"
+	AuthenticatorKerberos AuthenticatorType = "kerberos"
+	AuthenticatorTLS      AuthenticatorType = "tls"
 )
 
 const (
 	NifiAdminUsername = "admin"
+
+	// LoginIdentityProviderIdentifier is the provider set as `nifi.security.user.login.identity.provider`.
+	LoginIdentityProviderIdentifier = "login-identity-provider"
 )
 
 var (
-	SupportedAuthTypes = []AuthenticatorType{AuthenticatorTypeLDAP, AuthenticatorTypeOIDC, AuthenticatorStatic}
+	SupportedAuthTypes = []AuthenticatorType{AuthenticatorTypeLDAP, AuthenticatorTypeOIDC, AuthenticatorStatic, AuthenticatorKerberos, AuthenticatorTLS}
 )
 
+// Authentication combines the authenticators of every AuthenticationClass of the cluster.
 type Authentication struct {
 	Authenticators map[AuthenticatorType][]Authenticator
+
+	// initialAdmin is the initialAdmin configured on an AuthenticationClass of the cluster.
+	initialAdmin string
 }
 
 func GetAuthProvider(ctx context.Context, client *client.Client, authclass string) (*authv1alpha1.AuthenticationProvider, error) {
@@ -54,103 +64,321 @@ func NewAuthentication(
 	client *client.Client,
 	clusterName string,
 	auths []nifiv1alpha1.AuthenticationSpec,
+	tls *nifiv1alpha1.TlsSpec,
 ) (*Authentication, error) {
 	authenticators := make(map[AuthenticatorType][]Authenticator)
+	initialAdmin := ""
 	if len(auths) == 0 {
 		return nil, fmt.Errorf("no authentication specifications provided")
 	}
-	if len(auths) > 1 {
-		return nil, fmt.Errorf("multiple authentication specifications are not supported")
-	}
-	if len(auths[0].AuthenticationClass) == 0 {
-		return nil, fmt.Errorf("authentication class is required")
-	}
-	authLogger.Info("Creating authentication", "authClass", auths[0].AuthenticationClass)
 
+	authClasses := make(map[string]struct{}, len(auths))
 	for _, auth := range auths {
+		if len(auth.AuthenticationClass) == 0 {
+			return nil, fmt.Errorf("authentication class is required")
+		}
+		if _, exists := authClasses[auth.AuthenticationClass]; exists {
+			return nil, fmt.Errorf("authentication class %s is referenced more than once", auth.AuthenticationClass)
+		}
+		authClasses[auth.AuthenticationClass] = struct{}{}
+		authLogger.Info("Creating authentication", "authClass", auth.AuthenticationClass)
+
 		provider, err := GetAuthProvider(ctx, client, auth.AuthenticationClass)
 		if err != nil {
 			return nil, err
 		}
+		if provider == nil {
+			return nil, fmt.Errorf("authentication class %s not found or has no provider", auth.AuthenticationClass)
+		}
+
+		if auth.OidcGroups != nil && provider.OIDC == nil {
+			return nil, fmt.Errorf("authentication class %s is not an OIDC class, oidcGroups is not supported", auth.AuthenticationClass)
+		}
+		if auth.LdapGroups != nil && provider.LDAP == nil {
+			return nil, fmt.Errorf("authentication class %s is not an LDAP class, ldapGroups is not supported", auth.AuthenticationClass)
+		}
+		if auth.LdapStartTls && provider.LDAP == nil {
+			return nil, fmt.Errorf("authentication class %s is not an LDAP class, ldapStartTls is not supported", auth.AuthenticationClass)
+		}
+		if auth.InitialAdmin != "" && provider.Static != nil {
+			return nil, fmt.Errorf("authentication class %s is a static class, its `admin` user is the initial admin, initialAdmin is not supported", auth.AuthenticationClass)
+		}
+		if auth.InitialAdmin != "" {
+			// authorizers.xml holds a single initial admin.
+			if initialAdmin != "" && initialAdmin != auth.InitialAdmin {
+				return nil, fmt.Errorf("authentication class %s sets a different initialAdmin, only one initial admin is supported", auth.AuthenticationClass)
+			}
+			initialAdmin = auth.InitialAdmin
+		}
 
 		if provider.OIDC != nil && slices.Contains(SupportedAuthTypes, AuthenticatorTypeOIDC) {
-			oidcAuth := &oidcAuthenticator{clusterName: clusterName, config: auth.Oidc, provider: provider.OIDC}
+			if err := validateOIDCProvider(auth.AuthenticationClass, provider.OIDC); err != nil {
+				return nil, err
+			}
+			oidcAuth := &oidcAuthenticator{
+				clusterName:  clusterName,
+				config:       auth.Oidc,
+				groups:       auth.OidcGroups,
+				initialAdmin: auth.InitialAdmin,
+				provider:     provider.OIDC,
+			}
 			authenticators[AuthenticatorTypeOIDC] = append(authenticators[AuthenticatorTypeOIDC], oidcAuth)
 		} else if provider.LDAP != nil && slices.Contains(SupportedAuthTypes, AuthenticatorTypeLDAP) {
-			ldapAuth := &ldapAuthenticator{clusterName: clusterName, provider: provider.LDAP}
+			if err := validateLDAPProvider(auth.AuthenticationClass, provider.LDAP, auth.LdapStartTls); err != nil {
+				return nil, err
+			}
+			ldapAuth := &ldapAuthenticator{
+				clusterName:  clusterName,
+				groups:       auth.LdapGroups,
+				initialAdmin: auth.InitialAdmin,
+				startTls:     auth.LdapStartTls,
+				provider:     provider.LDAP,
+			}
+			if ldapAuth.getCASecretClass() != "" {
+				if ldapAuth.storePassword, err = GetStorePassword(ctx, client, clusterName); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := GetLdapSyncInterval(auth.LdapGroups); err != nil {
+				return nil, fmt.Errorf("ldap authentication class %s: %w", auth.AuthenticationClass, err)
+			}
 			authenticators[AuthenticatorTypeLDAP] = append(authenticators[AuthenticatorTypeLDAP], ldapAuth)
 		} else if provider.Static != nil && slices.Contains(SupportedAuthTypes, AuthenticatorStatic) {
 			staticAuth := &staticAuthenticator{clusterName: clusterName, provider: provider.Static}
 			authenticators[AuthenticatorStatic] = append(authenticators[AuthenticatorStatic], staticAuth)
+		} else if provider.Kerberos != nil && slices.Contains(SupportedAuthTypes, AuthenticatorKerberos) {
+			if provider.Kerberos.KerberosStorageClass == "" {
+				return nil, fmt.Errorf("kerberos authentication class %s requires a kerberosStorageClass", auth.AuthenticationClass)
+			}
+			kerberosAuth := &kerberosAuthenticator{clusterName: clusterName, initialAdmin: auth.InitialAdmin, provider: provider.Kerberos}
+			authenticators[AuthenticatorKerberos] = append(authenticators[AuthenticatorKerberos], kerberosAuth)
+		} else if provider.TLS != nil && slices.Contains(SupportedAuthTypes, AuthenticatorTLS) {
+			if err := validateTLSProvider(auth.AuthenticationClass, provider.TLS, tls); err != nil {
+				return nil, err
+			}
+			tlsAuth := &tlsAuthenticator{clusterName: clusterName, initialAdmin: auth.InitialAdmin, provider: provider.TLS}
+			authenticators[AuthenticatorTLS] = append(authenticators[AuthenticatorTLS], tlsAuth)
 		} else {
 			return nil, fmt.Errorf("unsupported authentication provider: %s", auth.AuthenticationClass)
 		}
 	}
 
-	return &Authentication{
+	if err := validateAuthenticators(authenticators, initialAdmin); err != nil {
+		return nil, err
+	}
+
+	authentication := &Authentication{
 		Authenticators: authenticators,
-	}, nil
+		initialAdmin:   initialAdmin,
+	}
+	// Without an initial admin nobody can manage the policies of the cluster, unless OIDC groups hold the admin policies.
+	if groups := authentication.GetOidcGroups(); authentication.GetInitialAdminIdentity() == "" && (groups == nil || len(groups.Admin) == 0) {
+		return nil, fmt.Errorf("no initial admin identity, set initialAdmin on the authentication class of the cluster")
+	}
+	return authentication, nil
+}
+
+// validateAuthenticators rejects the combinations NiFi cannot run together.
+func validateAuthenticators(authenticators map[AuthenticatorType][]Authenticator, initialAdmin string) error {
+	// nifi.properties holds a single OIDC configuration.
+	if len(authenticators[AuthenticatorTypeOIDC]) > 1 {
+		return fmt.Errorf("only one OIDC authentication class is supported, got %d", len(authenticators[AuthenticatorTypeOIDC]))
+	}
+	// The static authenticator manages the single `admin` user.
+	if len(authenticators[AuthenticatorStatic]) > 1 {
+		return fmt.Errorf("only one static authentication class is supported, got %d", len(authenticators[AuthenticatorStatic]))
+	}
+	// nifi.properties holds a single SPNEGO principal and keytab.
+	if len(authenticators[AuthenticatorKerberos]) > 1 {
+		return fmt.Errorf("only one kerberos authentication class is supported, got %d", len(authenticators[AuthenticatorKerberos]))
+	}
+	// nifi.properties selects a single login identity provider, NiFi does not enable OIDC next to it.
+	// The login providers are not chained. Client certificates need none and are combined with any
+	// authenticator, they are the break-glass login when the login provider is unavailable.
+	var loginIdentityProviders []AuthenticatorType
+	for _, authType := range SupportedAuthTypes {
+		for _, authenticator := range authenticators[authType] {
+			if authenticator.GetLoginIdentiryProvider(LoginIdentityProviderIdentifier) != "" {
+				loginIdentityProviders = append(loginIdentityProviders, authType)
+			}
+		}
+	}
+	if len(loginIdentityProviders) > 1 {
+		return fmt.Errorf("only one authentication class with a login identity provider is supported, got %v, "+
+			"login providers are not chained, use a TLS authentication class for break-glass access", loginIdentityProviders)
+	}
+	if len(loginIdentityProviders) > 0 && len(authenticators[AuthenticatorTypeOIDC]) > 0 {
+		return fmt.Errorf("OIDC authentication cannot be combined with the login identity provider of %s authentication, "+
+			"use a TLS authentication class for break-glass access", loginIdentityProviders[0])
+	}
+	// The operator queries the NiFi REST API as the static `admin` user, it must keep the admin policies.
+	if initialAdmin != "" && len(authenticators[AuthenticatorStatic]) > 0 {
+		return fmt.Errorf("initialAdmin %s cannot be combined with static authentication, its `admin` user is the initial admin", initialAdmin)
+	}
+	return nil
+}
+
+// validateTLSProvider checks that NiFi serves TLS and trusts the CA issuing the client certificates.
+func validateTLSProvider(authClass string, provider *authv1alpha1.TLSProvider, tls *nifiv1alpha1.TlsSpec) error {
+	if tls == nil {
+		return fmt.Errorf("tls authentication class %s requires clusterConfig.tls", authClass)
+	}
+	if provider.ClientCertSecretClass != "" && provider.ClientCertSecretClass != tls.ServerSecretClass {
+		return fmt.Errorf(
+			"tls authentication class %s uses the client certificate SecretClass %s, NiFi only trusts the server SecretClass %s",
+			authClass, provider.ClientCertSecretClass, tls.ServerSecretClass,
+		)
+	}
+	return nil
+}
+
+// getAuthenticators returns the authenticators ordered by SupportedAuthTypes, so the generated config is stable.
+func (a *Authentication) getAuthenticators() []Authenticator {
+	var authenticators []Authenticator
+	for _, authType := range SupportedAuthTypes {
+		authenticators = append(authenticators, a.Authenticators[authType]...)
+	}
+	return authenticators
 }
 
 func (a *Authentication) GetInitArgs() string {
-	for _, typedAuthenticator := range a.Authenticators {
-		if len(typedAuthenticator) == 1 {
-			return typedAuthenticator[0].GetInitArgs()
+	var args []string
+	for _, authenticator := range a.getAuthenticators() {
+		if arg := strings.TrimSpace(authenticator.GetInitArgs()); arg != "" {
+			args = append(args, arg)
 		}
 	}
-	return ""
+	return strings.Join(args, "\n")
 }
 
 func (a *Authentication) GetEnvVars() []corev1.EnvVar {
-	for _, typedAuthenticator := range a.Authenticators {
-		if len(typedAuthenticator) == 1 {
-			return typedAuthenticator[0].GetEnvVars()
-		}
+	var envVars []corev1.EnvVar
+	for _, authenticator := range a.getAuthenticators() {
+		envVars = append(envVars, authenticator.GetEnvVars()...)
 	}
-	return nil
+	return envVars
 }
 
+// GetVolumes returns the volumes of every authenticator, volumes shared by several authenticators,
+// e.g. the bind credentials of two LDAP classes using the same SecretClass, are only added once.
 func (a *Authentication) GetVolumes() []corev1.Volume {
-	for _, typedAuthenticator := range a.Authenticators {
-		if len(typedAuthenticator) == 1 {
-			return typedAuthenticator[0].GetVolumes()
+	var volumes []corev1.Volume
+	names := make(map[string]struct{})
+	for _, authenticator := range a.getAuthenticators() {
+		for _, volume := range authenticator.GetVolumes() {
+			if _, exists := names[volume.Name]; exists {
+				continue
+			}
+			names[volume.Name] = struct{}{}
+			volumes = append(volumes, volume)
 		}
 	}
-	return nil
+	return volumes
 }
 
 func (a *Authentication) GetVolumeMounts() []corev1.VolumeMount {
-	for _, typedAuthenticator := range a.Authenticators {
-		if len(typedAuthenticator) == 1 {
-			return typedAuthenticator[0].GetVolumeMounts()
+	var volumeMounts []corev1.VolumeMount
+	mountPaths := make(map[string]struct{})
+	for _, authenticator := range a.getAuthenticators() {
+		for _, volumeMount := range authenticator.GetVolumeMounts() {
+			if _, exists := mountPaths[volumeMount.MountPath]; exists {
+				continue
+			}
+			mountPaths[volumeMount.MountPath] = struct{}{}
+			volumeMounts = append(volumeMounts, volumeMount)
 		}
 	}
-	return nil
+	return volumeMounts
 }
 
 func (a *Authentication) ExtendNifiProperties() *properties.Properties {
-	for _, typedAuthenticator := range a.Authenticators {
-		if len(typedAuthenticator) == 1 {
-			return typedAuthenticator[0].ExtendNifiProperties()
+	var props *properties.Properties
+	for _, authenticator := range a.getAuthenticators() {
+		extra := authenticator.ExtendNifiProperties()
+		if extra == nil {
+			continue
+		}
+		if props == nil {
+			props = properties.NewProperties()
+		}
+		for _, key := range extra.Keys() {
+			value, _ := extra.Get(key)
+			props.Add(key, value)
 		}
 	}
-	return nil
+	return props
 }
 
+// GetLoginIdentiryProvider returns login-identity-providers.xml. NiFi uses a single login identity provider,
+// validateAuthenticators rejects several authenticators providing one.
+// It returns an empty string when no authenticator has a login identity provider, e.g. with client certificates only.
 func (a *Authentication) GetLoginIdentiryProvider() string {
-	for _, typedAuthenticator := range a.Authenticators {
-		if len(typedAuthenticator) > 0 {
-			return typedAuthenticator[0].GetLoginIdentiryProvider()
+	for _, authenticator := range a.getAuthenticators() {
+		if provider := authenticator.GetLoginIdentiryProvider(LoginIdentityProviderIdentifier); provider != "" {
+			return getIdentityProvider(provider)
 		}
 	}
 	return ""
 }
 
+// HasLoginIdentityProvider reports whether login-identity-providers.xml is generated.
+func (a *Authentication) HasLoginIdentityProvider() bool {
+	return a.GetLoginIdentiryProvider() != ""
+}
+
+// GetInitialAdminIdentity returns the identity granted the admin policies in authorizers.xml. A configured
+// initialAdmin takes precedence, otherwise the `admin` user managed by the operator wins, as the operator calls
+// the NiFi API with its credentials.
+func (a *Authentication) GetInitialAdminIdentity() string {
+	if a.initialAdmin != "" {
+		return a.initialAdmin
+	}
+	identity := ""
+	for _, authenticator := range a.getAuthenticators() {
+		candidate := authenticator.GetInitialAdminIdentity()
+		if candidate == NifiAdminUsername {
+			return candidate
+		}
+		if identity == "" {
+			identity = candidate
+		}
+	}
+	return identity
+}
+
+// GetAdminCredentials returns the credentials of the NiFi admin user by reading the secret
+// mounted into the NiFi pods. Only the static authenticator manages the password of a user,
+// it returns an empty username without static authentication, e.g. with LDAP or OIDC.
+func (a *Authentication) GetAdminCredentials(ctx context.Context, client *client.Client) (username, password string, err error) {
+	for _, volume := range a.GetVolumes() {
+		if volume.Name != NifiAdminUsername || volume.Secret == nil {
+			continue
+		}
+
+		secret := &corev1.Secret{}
+		key := ctrlclient.ObjectKey{Namespace: client.GetOwnerNamespace(), Name: volume.Secret.SecretName}
+		if err := client.Get(ctx, key, secret); err != nil {
+			return "", "", fmt.Errorf("failed to get admin credentials secret %s: %w", key.Name, err)
+		}
+
+		passwordBytes, ok := secret.Data[NifiAdminUsername]
+		if !ok {
+			return "", "", fmt.Errorf("admin credentials secret %s does not contain the key %s", key.Name, NifiAdminUsername)
+		}
+		return NifiAdminUsername, strings.TrimSpace(string(passwordBytes)), nil
+	}
+	return "", "", nil
+}
+
 type Authenticator interface {
 	GetEnvVars() []corev1.EnvVar
 	GetVolumes() []corev1.Volume
 	GetVolumeMounts() []corev1.VolumeMount
 	ExtendNifiProperties() *properties.Properties
-	GetLoginIdentiryProvider() string
+	// GetLoginIdentiryProvider returns the `<provider>` element of the authenticator with the given identifier.
+	GetLoginIdentiryProvider(identifier string) string
 	GetInitArgs() string
+	// GetInitialAdminIdentity returns the identity of the initial admin, it may be a gomplate expression
+	// rendered when the pod starts. An empty identity means no initial admin.
+	GetInitialAdminIdentity() string
 }
@@ -0,0 +1,279 @@
+package security
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func newLDAPAuthenticator(secretClass string) *ldapAuthenticator {
+	return &ldapAuthenticator{clusterName: "simple-nifi", provider: &authv1alpha1.LDAPProvider{
+		Hostname:        "openldap",
+		Port:            1389,
+		SearchBase:      "ou=users,dc=example,dc=org",
+		BindCredentials: &commonsv1alpha1.Credentials{SecretClass: secretClass},
+		LDAPFieldNames:  &authv1alpha1.LDAPFieldNames{Uid: "uid"},
+	}}
+}
+
+func newStaticAuthenticator() *staticAuthenticator {
+	return &staticAuthenticator{clusterName: "simple-nifi", provider: &authv1alpha1.StaticProvider{
+		UserCredentialsSecret: &authv1alpha1.StaticCredentialsSecret{Name: "nifi-admin"},
+	}}
+}
+
+func TestAuthentication_CombinesAuthenticators(t *testing.T) {
+	auth := &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{
+		AuthenticatorStatic:   {newStaticAuthenticator()},
+		AuthenticatorTypeLDAP: {newLDAPAuthenticator("ldap-bind"), newLDAPAuthenticator("ldap-bind")},
+	}}
+
+	volumeNames := []string{}
+	for _, volume := range auth.GetVolumes() {
+		volumeNames = append(volumeNames, volume.Name)
+	}
+	// The LDAP classes share their bind credentials volume.
+	if expected := "ldap-bind-bind-credentials,admin"; strings.Join(volumeNames, ",") != expected {
+		t.Errorf("expected volumes %s, got %v", expected, volumeNames)
+	}
+	if got := len(auth.GetVolumeMounts()); got != 2 {
+		t.Errorf("expected 2 volume mounts, got %d", got)
+	}
+	if got := auth.GetInitArgs(); !strings.Contains(got, "NIFI_ADMIN_PASSWORD") {
+		t.Errorf("expected the init args of the static authenticator, got %q", got)
+	}
+	// The operator managed admin wins over the LDAP bind user.
+	if got := auth.GetInitialAdminIdentity(); got != NifiAdminUsername {
+		t.Errorf("expected initial admin %s, got %q", NifiAdminUsername, got)
+	}
+}
+
+func TestAuthentication_LoginIdentityProvider(t *testing.T) {
+	auth := &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{
+		AuthenticatorTypeLDAP: {newLDAPAuthenticator("ldap-bind")},
+		AuthenticatorTLS:      {&tlsAuthenticator{clusterName: "simple-nifi", provider: &authv1alpha1.TLSProvider{}}},
+	}}
+
+	got := auth.GetLoginIdentiryProvider()
+	// Compare with collapsed whitespace, so the checks do not depend on the indentation.
+	collapsed := strings.Join(strings.Fields(got), " ")
+	if expected := "<identifier>login-identity-provider</identifier> <class>org.apache.nifi.ldap.LdapProvider</class>"; !strings.Contains(collapsed, expected) {
+		t.Errorf("expected %q in:\n%s", expected, got)
+	}
+	if strings.Count(got, "<provider>") != 1 {
+		t.Errorf("expected a single provider:\n%s", got)
+	}
+	if !strings.HasPrefix(got, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`) {
+		t.Errorf("expected the XML declaration on the first line:\n%s", got)
+	}
+	var document struct{}
+	if err := xml.Unmarshal([]byte(got), &document); err != nil {
+		t.Errorf("expected well-formed XML: %v", err)
+	}
+}
+
+func TestValidateAuthenticators(t *testing.T) {
+	oidc := &oidcAuthenticator{clusterName: "simple-nifi"}
+
+	tests := []struct {
+		name           string
+		authenticators map[AuthenticatorType][]Authenticator
+		initialAdmin   string
+		wantErr        string
+	}{
+		{
+			name: "ldap and tls",
+			authenticators: map[AuthenticatorType][]Authenticator{
+				AuthenticatorTypeLDAP: {newLDAPAuthenticator("ldap-bind")},
+				AuthenticatorTLS:      {&tlsAuthenticator{clusterName: "simple-nifi", provider: &authv1alpha1.TLSProvider{}}},
+			},
+		},
+		{
+			name: "ldap and static",
+			authenticators: map[AuthenticatorType][]Authenticator{
+				AuthenticatorTypeLDAP: {newLDAPAuthenticator("ldap-bind")},
+				AuthenticatorStatic:   {newStaticAuthenticator()},
+			},
+			wantErr: "only one authentication class with a login identity provider",
+		},
+		{
+			name:           "two ldap",
+			authenticators: map[AuthenticatorType][]Authenticator{AuthenticatorTypeLDAP: {newLDAPAuthenticator("ldap-a"), newLDAPAuthenticator("ldap-b")}},
+			wantErr:        "only one authentication class with a login identity provider",
+		},
+		{
+			name: "ldap and oidc",
+			authenticators: map[AuthenticatorType][]Authenticator{
+				AuthenticatorTypeLDAP: {newLDAPAuthenticator("ldap-bind")},
+				AuthenticatorTypeOIDC: {oidc},
+			},
+			wantErr: "cannot be combined",
+		},
+		{
+			name: "oidc and tls",
+			authenticators: map[AuthenticatorType][]Authenticator{
+				AuthenticatorTypeOIDC: {oidc},
+				AuthenticatorTLS:      {&tlsAuthenticator{clusterName: "simple-nifi", provider: &authv1alpha1.TLSProvider{}}},
+			},
+		},
+		{
+			name:           "two oidc",
+			authenticators: map[AuthenticatorType][]Authenticator{AuthenticatorTypeOIDC: {oidc, oidc}},
+			wantErr:        "only one OIDC authentication class",
+		},
+		{
+			name:           "two static",
+			authenticators: map[AuthenticatorType][]Authenticator{AuthenticatorStatic: {newStaticAuthenticator(), newStaticAuthenticator()}},
+			wantErr:        "only one static authentication class",
+		},
+		{
+			name: "static and oidc",
+			authenticators: map[AuthenticatorType][]Authenticator{
+				AuthenticatorStatic:   {newStaticAuthenticator()},
+				AuthenticatorTypeOIDC: {oidc},
+			},
+			wantErr: "cannot be combined",
+		},
+		{
+			name: "static and tls with initial admin",
+			authenticators: map[AuthenticatorType][]Authenticator{
+				AuthenticatorStatic: {newStaticAuthenticator()},
+				AuthenticatorTLS:    {&tlsAuthenticator{clusterName: "simple-nifi", initialAdmin: "alice", provider: &authv1alpha1.TLSProvider{}}},
+			},
+			initialAdmin: "alice",
+			wantErr:      "cannot be combined with static authentication",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAuthenticators(tt.authenticators, tt.initialAdmin)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewAuthentication_InitialAdmin(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(t,
+		&authv1alpha1.AuthenticationClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "kerberos"},
+			Spec: authv1alpha1.AuthenticationClassSpec{AuthenticationProvider: &authv1alpha1.AuthenticationProvider{
+				Kerberos: &authv1alpha1.KerberosProvider{KerberosStorageClass: "kerberos"},
+			}},
+		},
+		&authv1alpha1.AuthenticationClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "ldap"},
+			Spec: authv1alpha1.AuthenticationClassSpec{AuthenticationProvider: &authv1alpha1.AuthenticationProvider{
+				LDAP: &authv1alpha1.LDAPProvider{Hostname: "openldap", Port: 1389, SearchBase: "ou=users,dc=example,dc=org"},
+			}},
+		},
+		&authv1alpha1.AuthenticationClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "static"},
+			Spec: authv1alpha1.AuthenticationClassSpec{AuthenticationProvider: &authv1alpha1.AuthenticationProvider{
+				Static: &authv1alpha1.StaticProvider{UserCredentialsSecret: &authv1alpha1.StaticCredentialsSecret{Name: "nifi-admin"}},
+			}},
+		},
+		&authv1alpha1.AuthenticationClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "oidc"},
+			Spec: authv1alpha1.AuthenticationClassSpec{AuthenticationProvider: &authv1alpha1.AuthenticationProvider{
+				OIDC: &authv1alpha1.OIDCProvider{Hostname: "keycloak", PrincipalClaim: "preferred_username"},
+			}},
+		},
+		&authv1alpha1.AuthenticationClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "tls"},
+			Spec: authv1alpha1.AuthenticationClassSpec{AuthenticationProvider: &authv1alpha1.AuthenticationProvider{
+				TLS: &authv1alpha1.TLSProvider{},
+			}},
+		},
+	)
+
+	tls := &nifiv1alpha1.TlsSpec{ServerSecretClass: "tls"}
+	for _, authClass := range []string{"kerberos", "ldap", "oidc", "tls"} {
+		auths := []nifiv1alpha1.AuthenticationSpec{{AuthenticationClass: authClass, Oidc: &authv1alpha1.OidcSpec{ClientCredentialsSecret: "nifi-oidc-client"}}}
+		if _, err := NewAuthentication(ctx, c, "simple-nifi", auths, tls); err == nil {
+			t.Errorf("%s: expected an error without initial admin", authClass)
+		}
+
+		auths[0].InitialAdmin = "alice"
+		auth, err := NewAuthentication(ctx, c, "simple-nifi", auths, tls)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", authClass, err)
+		}
+		if got := auth.GetInitialAdminIdentity(); got != "alice" {
+			t.Errorf("%s: expected the initial admin alice, got %q", authClass, got)
+		}
+	}
+
+	// The configured initial admin takes precedence over the `admin` client certificate.
+	auths := []nifiv1alpha1.AuthenticationSpec{{AuthenticationClass: "tls"}, {AuthenticationClass: "ldap", InitialAdmin: "alice"}}
+	auth, err := NewAuthentication(ctx, c, "simple-nifi", auths, tls)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := auth.GetInitialAdminIdentity(); got != "alice" {
+		t.Errorf("expected the configured initial admin alice, got %q", got)
+	}
+
+	// The OIDC admin groups administer the cluster without an initial admin.
+	auths = []nifiv1alpha1.AuthenticationSpec{{
+		AuthenticationClass: "oidc",
+		Oidc:                &authv1alpha1.OidcSpec{ClientCredentialsSecret: "nifi-oidc-client"},
+		OidcGroups:          &nifiv1alpha1.OidcGroupsSpec{Admin: []string{"nifi-admins"}},
+	}}
+	if _, err := NewAuthentication(ctx, c, "simple-nifi", auths, tls); err != nil {
+		t.Errorf("unexpected error with OIDC admin groups: %v", err)
+	}
+
+	auths = []nifiv1alpha1.AuthenticationSpec{{AuthenticationClass: "static", InitialAdmin: "alice"}}
+	if _, err := NewAuthentication(ctx, c, "simple-nifi", auths, nil); err == nil {
+		t.Error("expected an error for initialAdmin on a static authentication class")
+	}
+
+	// The operator queries the NiFi REST API as the static `admin` user.
+	auths = []nifiv1alpha1.AuthenticationSpec{{AuthenticationClass: "static"}, {AuthenticationClass: "tls", InitialAdmin: "alice"}}
+	if _, err := NewAuthentication(ctx, c, "simple-nifi", auths, tls); err == nil {
+		t.Error("expected an error for initialAdmin combined with a static authentication class")
+	}
+}
+
+func TestAuthentication_GetAdminCredentials(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(t, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "nifi-admin", Namespace: "default"},
+		Data:       map[string][]byte{NifiAdminUsername: []byte("secret\n")},
+	})
+
+	static := &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{AuthenticatorStatic: {newStaticAuthenticator()}}}
+	username, password, err := static.GetAdminCredentials(ctx, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if username != NifiAdminUsername || password != "secret" {
+		t.Errorf("expected the static admin credentials, got %q/%q", username, password)
+	}
+
+	// The operator has no password of an OIDC user, even of the initial admin.
+	oidc := &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{
+		AuthenticatorTypeOIDC: {&oidcAuthenticator{clusterName: "simple-nifi", initialAdmin: "alice", provider: &authv1alpha1.OIDCProvider{}}},
+	}}
+	if username, _, err := oidc.GetAdminCredentials(ctx, c); err != nil || username != "" {
+		t.Errorf("expected no admin credentials with OIDC, got %q, %v", username, err)
+	}
+}
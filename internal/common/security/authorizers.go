@@ -0,0 +1,117 @@
+package security
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/zncdatadev/operator-go/pkg/util"
+)
+
+const (
+	// NodeIdentity is the subject of the certificates the secret-operator issues for the NiFi pods,
+	// the nodes of a secured cluster identify themselves with it.
+	NodeIdentity = "CN=generated certificate for pod"
+
+	AuthorizerIdentifier               = "authorizer"
+	FileUserGroupProviderIdentifier    = "file-user-group-provider"
+	FileAccessPolicyProviderIdentifier = "file-access-policy-provider"
+	LdapUserGroupProviderIdentifier    = "ldap-user-group-provider"
+	// CompositeUserGroupProviderIdentifier combines the file-user-group-provider with the LDAP user group providers.
+	CompositeUserGroupProviderIdentifier = "composite-user-group-provider"
+)
+
+// GetAuthorizers returns authorizers.xml, a managed authorizer backed by a file-user-group-provider and a
+// file-access-policy-provider. The initial admin is derived from the authenticator, every node identity
+// gets the policies a node of the cluster needs.
+//
+// The users and authorizations files are written to dataDir, so the users and policies created in NiFi
+// survive restarts. NiFi only seeds the initial identities when these files do not exist yet.
+//
+// With `ldapGroups` set on an LDAP class, the users and groups of the LDAP server are synced by an
+// ldap-user-group-provider, a composite provider combines them with the users managed in NiFi. NiFi refuses
+// a user provided by several providers, so the LDAP admin is then not seeded into the file-user-group-provider.
+func (a *Authentication) GetAuthorizers(dataDir string, nodeIdentities []string) string {
+	adminIdentity := a.GetInitialAdminIdentity()
+
+	var ldapAuthenticators []*ldapAuthenticator
+	ldapAdmin := false
+	for _, authenticator := range a.Authenticators[AuthenticatorTypeLDAP] {
+		if ldap := authenticator.(*ldapAuthenticator); ldap.groups != nil {
+			ldapAuthenticators = append(ldapAuthenticators, ldap)
+			ldapAdmin = ldapAdmin || ldap.GetInitialAdminIdentity() == adminIdentity
+		}
+	}
+
+	userIdentities := make([]string, 0, len(nodeIdentities)+1)
+	if adminIdentity != "" && !ldapAdmin {
+		userIdentities = append(userIdentities, adminIdentity)
+	}
+	userIdentities = append(userIdentities, nodeIdentities...)
+
+	userGroupProvider := `
+	<userGroupProvider>
+		<identifier>` + FileUserGroupProviderIdentifier + `</identifier>
+		<class>org.apache.nifi.authorization.FileUserGroupProvider</class>
+		<property name="Users File">` + path.Join(dataDir, "users.xml") + `</property>`
+	for i, identity := range userIdentities {
+		userGroupProvider += fmt.Sprintf(`
+		<property name="Initial User Identity %d">%s</property>`, i+1, identity)
+	}
+	userGroupProvider += `
+	</userGroupProvider>`
+
+	policyUserGroupProvider := FileUserGroupProviderIdentifier
+	if len(ldapAuthenticators) > 0 {
+		compositeProvider := `
+	<userGroupProvider>
+		<identifier>` + CompositeUserGroupProviderIdentifier + `</identifier>
+		<class>org.apache.nifi.authorization.CompositeConfigurableUserGroupProvider</class>
+		<property name="Configurable User Group Provider">` + FileUserGroupProviderIdentifier + `</property>`
+		for i, authenticator := range ldapAuthenticators {
+			identifier := LdapUserGroupProviderIdentifier
+			if len(ldapAuthenticators) > 1 {
+				identifier = fmt.Sprintf("%s-%d", LdapUserGroupProviderIdentifier, i+1)
+			}
+			userGroupProvider += authenticator.getUserGroupProvider(identifier)
+			compositeProvider += fmt.Sprintf(`
+		<property name="User Group Provider %d">%s</property>`, i+1, identifier)
+		}
+		userGroupProvider += compositeProvider + `
+	</userGroupProvider>`
+		policyUserGroupProvider = CompositeUserGroupProviderIdentifier
+	}
+
+	accessPolicyProvider := `
+	<accessPolicyProvider>
+		<identifier>` + FileAccessPolicyProviderIdentifier + `</identifier>
+		<class>org.apache.nifi.authorization.FileAccessPolicyProvider</class>
+		<property name="User Group Provider">` + policyUserGroupProvider + `</property>
+		<property name="Authorizations File">` + path.Join(dataDir, "authorizations.xml") + `</property>
+		<property name="Initial Admin Identity">` + adminIdentity + `</property>`
+	for i, identity := range nodeIdentities {
+		accessPolicyProvider += fmt.Sprintf(`
+		<property name="Node Identity %d">%s</property>`, i+1, identity)
+	}
+	accessPolicyProvider += `
+		<property name="Node Group"></property>
+	</accessPolicyProvider>`
+
+	authorizer := `
+	<authorizer>
+		<identifier>` + AuthorizerIdentifier + `</identifier>
+		<class>org.apache.nifi.authorization.StandardManagedAuthorizer</class>
+		<property name="Access Policy Provider">` + FileAccessPolicyProviderIdentifier + `</property>
+	</authorizer>`
+
+	snippet := []string{
+		`<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<authorizers>`,
+		userGroupProvider,
+		accessPolicyProvider,
+		authorizer,
+		`</authorizers>
+`,
+	}
+	return util.IndentTab4Spaces(strings.Join(snippet, "\n"))
+}
@@ -0,0 +1,121 @@
+package security
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func TestGetAuthorizers(t *testing.T) {
+	auth := &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{
+		AuthenticatorStatic: {&staticAuthenticator{clusterName: "simple-nifi", provider: &authv1alpha1.StaticProvider{}}},
+	}}
+
+	got := auth.GetAuthorizers("/kubedoop/data/data-repository", []string{NodeIdentity})
+
+	for _, expected := range []string{
+		`<property name="Users File">/kubedoop/data/data-repository/users.xml</property>`,
+		`<property name="Authorizations File">/kubedoop/data/data-repository/authorizations.xml</property>`,
+		`<property name="Initial User Identity 1">admin</property>`,
+		`<property name="Initial User Identity 2">CN=generated certificate for pod</property>`,
+		`<property name="Initial Admin Identity">admin</property>`,
+		`<property name="Node Identity 1">CN=generated certificate for pod</property>`,
+		`<property name="Access Policy Provider">file-access-policy-provider</property>`,
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+
+	var document struct{}
+	if err := xml.Unmarshal([]byte(got), &document); err != nil {
+		t.Errorf("expected well-formed XML: %v", err)
+	}
+}
+
+func TestGetInitialAdminIdentity_LDAP(t *testing.T) {
+	withBindCredentials := &ldapAuthenticator{provider: &authv1alpha1.LDAPProvider{
+		BindCredentials: &commonsv1alpha1.Credentials{SecretClass: "ldap-bind"},
+	}}
+	expected := `{{ file.Read "/kubedoop/secret/ldap-bind/username" | strings.TrimSpace }}`
+	if got := withBindCredentials.GetInitialAdminIdentity(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	withBindCredentials.initialAdmin = "uid=alice,ou=users,dc=example,dc=org"
+	if got := withBindCredentials.GetInitialAdminIdentity(); got != withBindCredentials.initialAdmin {
+		t.Errorf("expected the configured initial admin, got %q", got)
+	}
+
+	anonymous := &ldapAuthenticator{provider: &authv1alpha1.LDAPProvider{}}
+	if got := anonymous.GetInitialAdminIdentity(); got != "" {
+		t.Errorf("expected no initial admin without bind credentials, got %q", got)
+	}
+
+	auth := &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{AuthenticatorTypeLDAP: {anonymous}}}
+	if got := auth.GetAuthorizers("/data", nil); strings.Contains(got, "Initial User Identity") {
+		t.Errorf("expected no initial user identities:\n%s", got)
+	}
+}
+
+func TestGetAuthorizers_LDAPUserGroupProvider(t *testing.T) {
+	ldap := newLDAPAuthenticator("ldap-bind")
+	ldap.provider.LDAPFieldNames.Group = "memberof"
+	ldap.groups = &nifiv1alpha1.LdapGroupsSpec{SearchBase: "ou=groups,dc=example,dc=org", SyncInterval: "5m"}
+	auth := &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{AuthenticatorTypeLDAP: {ldap}}}
+
+	got := auth.GetAuthorizers("/kubedoop/data/data-repository", nil)
+	// Compare with collapsed whitespace, so the checks do not depend on the indentation.
+	collapsed := strings.Join(strings.Fields(got), " ")
+	for _, expected := range []string{
+		"<identifier>ldap-user-group-provider</identifier> <class>org.apache.nifi.ldap.tenants.LdapUserGroupProvider</class>",
+		`<property name="Manager DN">{{ file.Read "/kubedoop/secret/ldap-bind/username" | strings.TrimSpace }}</property>`,
+		`<property name="Manager Password">{{ file.Read "/kubedoop/secret/ldap-bind/password" | strings.TrimSpace }}</property>`,
+		`<property name="Sync Interval">300 secs</property>`,
+		`<property name="User Search Base">ou=users,dc=example,dc=org</property>`,
+		`<property name="User Group Name Attribute">memberof</property>`,
+		`<property name="Group Search Base">ou=groups,dc=example,dc=org</property>`,
+		`<property name="Group Member Attribute">member</property>`,
+		`<property name="Configurable User Group Provider">file-user-group-provider</property>`,
+		`<property name="User Group Provider 1">ldap-user-group-provider</property>`,
+		`<property name="User Group Provider">composite-user-group-provider</property>`,
+	} {
+		if !strings.Contains(collapsed, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+
+	// The ldap-user-group-provider provides the bind user, the file-user-group-provider must not claim it too.
+	if strings.Contains(got, "Initial User Identity") {
+		t.Errorf("expected no initial user identities:\n%s", got)
+	}
+	if expected := `<property name="Initial Admin Identity">{{ file.Read "/kubedoop/secret/ldap-bind/username" | strings.TrimSpace }}</property>`; !strings.Contains(collapsed, expected) {
+		t.Errorf("expected %q in:\n%s", expected, got)
+	}
+
+	var document struct{}
+	if err := xml.Unmarshal([]byte(got), &document); err != nil {
+		t.Errorf("expected well-formed XML: %v", err)
+	}
+
+	// Without ldapGroups the users are not synced, the file-user-group-provider holds the admin.
+	ldap.groups = nil
+	got = auth.GetAuthorizers("/kubedoop/data/data-repository", nil)
+	if strings.Contains(got, LdapUserGroupProviderIdentifier) || strings.Contains(got, CompositeUserGroupProviderIdentifier) {
+		t.Errorf("expected no LDAP user group provider without ldapGroups:\n%s", got)
+	}
+	if !strings.Contains(got, "Initial User Identity 1") {
+		t.Errorf("expected the admin as initial user identity:\n%s", got)
+	}
+
+	for _, syncInterval := range []string{"30", "1s"} {
+		if _, err := GetLdapSyncInterval(&nifiv1alpha1.LdapGroupsSpec{SyncInterval: syncInterval}); err == nil {
+			t.Errorf("expected an error for the sync interval %q", syncInterval)
+		}
+	}
+}
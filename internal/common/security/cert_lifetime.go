@@ -0,0 +1,62 @@
+package security
+
+import (
+	"fmt"
+	"time"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+const (
+	// NodeRestartTime is the time a restarted node is given to mount a newly issued certificate and rejoin
+	// the cluster, before the next node is restarted.
+	NodeRestartTime = 10 * time.Minute
+
+	// minCertLifetime is the shortest certificate lifetime accepted for a single node.
+	minCertLifetime = time.Hour
+
+	// certRestartShare is the share of the certificate lifetime left when the rotation starts, the nodes
+	// are restarted once 80% of the lifetime has passed.
+	certRestartShare = 5
+)
+
+// GetNodeCount returns the number of nodes of all role groups.
+func GetNodeCount(nodes *nifiv1alpha1.NodesSpec) int32 {
+	if nodes == nil {
+		return 0
+	}
+	var count int32
+	for _, roleGroup := range nodes.RoleGroups {
+		if roleGroup.Replicas == nil {
+			count++
+			continue
+		}
+		count += *roleGroup.Replicas
+	}
+	return count
+}
+
+// GetCertLifetime returns the lifetime requested for the server certificate, zero for the lifetime of the SecretClass.
+// The lifetime must leave the time to restart the nodes one at a time, at most half of it is spent on the rotation.
+func GetCertLifetime(tls *nifiv1alpha1.TlsSpec, nodes int32) (time.Duration, error) {
+	if tls == nil || tls.CertLifetime == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(tls.CertLifetime)
+	if err != nil {
+		return 0, fmt.Errorf("invalid certificate lifetime %q: %w", tls.CertLifetime, err)
+	}
+	minLifetime := max(minCertLifetime, 2*time.Duration(nodes)*NodeRestartTime)
+	if d < minLifetime {
+		return 0, fmt.Errorf("certificate lifetime %q is shorter than %s, the minimum to restart %d nodes one at a time", tls.CertLifetime, minLifetime, nodes)
+	}
+	return d, nil
+}
+
+// GetCertRestartWindow returns how long before its certificate expires a node is restarted. The window gives
+// NodeRestartTime to every node and starts once 80% of the lifetime has passed at the latest, so all nodes are
+// restarted one at a time before the certificates issued together expire. A zero lifetime, the unknown lifetime
+// of the SecretClass, only accounts for the nodes.
+func GetCertRestartWindow(lifetime time.Duration, nodes int32) time.Duration {
+	return max(time.Duration(nodes)*NodeRestartTime, lifetime/certRestartShare)
+}
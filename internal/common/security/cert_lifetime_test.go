@@ -0,0 +1,69 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/utils/ptr"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func TestGetCertLifetime(t *testing.T) {
+	tests := []struct {
+		tls      *nifiv1alpha1.TlsSpec
+		nodes    int32
+		expected time.Duration
+		wantErr  bool
+	}{
+		{tls: nil, nodes: 3},
+		{tls: &nifiv1alpha1.TlsSpec{ServerSecretClass: "tls"}, nodes: 3},
+		{tls: &nifiv1alpha1.TlsSpec{CertLifetime: "24h"}, nodes: 3, expected: 24 * time.Hour},
+		{tls: &nifiv1alpha1.TlsSpec{CertLifetime: "1h"}, nodes: 3, expected: time.Hour},
+		{tls: &nifiv1alpha1.TlsSpec{CertLifetime: "30m"}, nodes: 1, wantErr: true},
+		{tls: &nifiv1alpha1.TlsSpec{CertLifetime: "1h"}, nodes: 4, wantErr: true},
+		{tls: &nifiv1alpha1.TlsSpec{CertLifetime: "7d"}, nodes: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := GetCertLifetime(tt.tls, tt.nodes)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("GetCertLifetime(%+v, %d): unexpected error %v", tt.tls, tt.nodes, err)
+		}
+		if got != tt.expected {
+			t.Errorf("GetCertLifetime(%+v, %d): expected %s, got %s", tt.tls, tt.nodes, tt.expected, got)
+		}
+	}
+}
+
+func TestGetCertRestartWindow(t *testing.T) {
+	tests := []struct {
+		lifetime time.Duration
+		nodes    int32
+		expected time.Duration
+	}{
+		// The rotation starts at 80% of the lifetime.
+		{lifetime: 24 * time.Hour, nodes: 3, expected: 24 * time.Hour / 5},
+		// Every node gets NodeRestartTime when the lifetime is short.
+		{lifetime: time.Hour, nodes: 3, expected: 3 * NodeRestartTime},
+		// The lifetime of the SecretClass is not known.
+		{lifetime: 0, nodes: 2, expected: 2 * NodeRestartTime},
+	}
+	for _, tt := range tests {
+		if got := GetCertRestartWindow(tt.lifetime, tt.nodes); got != tt.expected {
+			t.Errorf("GetCertRestartWindow(%s, %d): expected %s, got %s", tt.lifetime, tt.nodes, tt.expected, got)
+		}
+	}
+}
+
+func TestGetNodeCount(t *testing.T) {
+	nodes := &nifiv1alpha1.NodesSpec{RoleGroups: map[string]nifiv1alpha1.RoleGroupSpec{
+		"default": {Replicas: ptr.To[int32](3)},
+		"small":   {},
+	}}
+	if got := GetNodeCount(nodes); got != 4 {
+		t.Errorf("expected 4 nodes, got %d", got)
+	}
+	if got := GetNodeCount(nil); got != 0 {
+		t.Errorf("expected no nodes, got %d", got)
+	}
+}
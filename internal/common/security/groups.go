@@ -0,0 +1,243 @@
+package security
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/zncdatadev/operator-go/pkg/util"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+const (
+	// DefaultOidcGroupsClaim is the claim of the ID token holding the groups of the user.
+	DefaultOidcGroupsClaim = "groups"
+
+	policyRead  = "R"
+	policyWrite = "W"
+)
+
+// accessPolicy grants an action on a global NiFi resource.
+type accessPolicy struct {
+	resource string
+	action   string
+}
+
+var (
+	// adminPolicies are the policies NiFi grants the initial admin, plus the remaining global resources.
+	adminPolicies = []accessPolicy{
+		{"/flow", policyRead},
+		{"/controller", policyRead},
+		{"/controller", policyWrite},
+		{"/tenants", policyRead},
+		{"/tenants", policyWrite},
+		{"/policies", policyRead},
+		{"/policies", policyWrite},
+		{"/restricted-components", policyWrite},
+		{"/parameter-contexts", policyRead},
+		{"/parameter-contexts", policyWrite},
+		{"/provenance", policyRead},
+		{"/counters", policyRead},
+		{"/counters", policyWrite},
+		{"/system", policyRead},
+	}
+	operatorPolicies = []accessPolicy{
+		{"/flow", policyRead},
+		{"/controller", policyRead},
+		{"/parameter-contexts", policyRead},
+		{"/provenance", policyRead},
+		{"/counters", policyRead},
+		{"/counters", policyWrite},
+		{"/system", policyRead},
+	}
+	readOnlyPolicies = []accessPolicy{
+		{"/flow", policyRead},
+	}
+	// nodePolicies let the nodes proxy the requests of the users, see the seeding of the Node Identities.
+	nodePolicies = []accessPolicy{
+		{"/proxy", policyWrite},
+	}
+)
+
+// getTenantIdentifier returns the identifier NiFi derives for a tenant or a policy, a name based UUID
+// like java.util.UUID.nameUUIDFromBytes.
+func getTenantIdentifier(name string) string {
+	sum := md5.Sum([]byte(name))
+	sum[6] = sum[6]&0x0f | 0x30
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// getGroupIdentifier returns the identifier of a group, distinct from the identifier of a user of the same name.
+func getGroupIdentifier(name string) string {
+	return getTenantIdentifier("group:" + name)
+}
+
+// mapIdentity applies the identity mapping of nifi.properties, NiFi stores the mapped identities.
+func (a *Authentication) mapIdentity(identity string) string {
+	if len(a.Authenticators[AuthenticatorTLS]) == 0 {
+		return identity
+	}
+	return regexp.MustCompile(ClientCertIdentityPattern).ReplaceAllString(identity, ClientCertIdentityValue)
+}
+
+func escapeXML(value string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
+
+// GetOidcGroups returns the groups of the OIDC authentication class, nil without OIDC or without groups.
+func (a *Authentication) GetOidcGroups() *nifiv1alpha1.OidcGroupsSpec {
+	for _, authenticator := range a.Authenticators[AuthenticatorTypeOIDC] {
+		if oidc, ok := authenticator.(*oidcAuthenticator); ok && oidc.groups != nil {
+			return oidc.groups
+		}
+	}
+	return nil
+}
+
+// HasGroupPolicies reports whether policies are granted to OIDC groups, the users and authorizations files
+// are then seeded by the operator instead of NiFi.
+func (a *Authentication) HasGroupPolicies() bool {
+	groups := a.GetOidcGroups()
+	return groups != nil && len(groups.Admin)+len(groups.Operator)+len(groups.ReadOnly) > 0
+}
+
+// getGroupPolicies returns the groups granted each policy, a group listed in several policy sets gets
+// every policy of these sets.
+func getGroupPolicies(groups *nifiv1alpha1.OidcGroupsSpec) map[accessPolicy][]string {
+	grants := map[accessPolicy][]string{}
+	if groups == nil {
+		return grants
+	}
+	for _, set := range []struct {
+		groups   []string
+		policies []accessPolicy
+	}{
+		{groups.Admin, adminPolicies},
+		{groups.Operator, operatorPolicies},
+		{groups.ReadOnly, readOnlyPolicies},
+	} {
+		for _, policy := range set.policies {
+			for _, group := range set.groups {
+				if !slices.Contains(grants[policy], group) {
+					grants[policy] = append(grants[policy], group)
+				}
+			}
+		}
+	}
+	return grants
+}
+
+// getOidcGroupNames returns the sorted names of the groups of every policy set.
+func getOidcGroupNames(groups *nifiv1alpha1.OidcGroupsSpec) []string {
+	if groups == nil {
+		return nil
+	}
+	names := slices.Concat(groups.Admin, groups.Operator, groups.ReadOnly)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// GetInitialTenants returns the users file seeding the initial admin, the node identities and the OIDC groups.
+// NiFi matches the groups of the groups claim to these groups by name.
+func (a *Authentication) GetInitialTenants(nodeIdentities []string) string {
+	groups := a.GetOidcGroups()
+
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<tenants>
+	<groups>`)
+	for _, name := range getOidcGroupNames(groups) {
+		fmt.Fprintf(&sb, `
+		<group identifier="%s" name="%s"/>`, getGroupIdentifier(name), escapeXML(name))
+	}
+	sb.WriteString(`
+	</groups>
+	<users>`)
+	for _, identity := range slices.Concat([]string{a.GetInitialAdminIdentity()}, nodeIdentities) {
+		// The OIDC admin groups may administer the cluster without an initial admin.
+		if identity == "" {
+			continue
+		}
+		identity = a.mapIdentity(identity)
+		fmt.Fprintf(&sb, `
+		<user identifier="%s" identity="%s"/>`, getTenantIdentifier(identity), escapeXML(identity))
+	}
+	sb.WriteString(`
+	</users>
+</tenants>
+`)
+	return util.IndentTab4Spaces(sb.String())
+}
+
+// GetInitialAuthorizations returns the authorizations file granting the admin policies to the initial admin,
+// the proxy policy to the nodes and the policy sets to the OIDC groups.
+//
+// The policies cover the global resources only, the process group policies depend on the identifier of the
+// root process group and are granted in NiFi.
+func (a *Authentication) GetInitialAuthorizations(nodeIdentities []string) string {
+	grants := map[accessPolicy]struct {
+		users  []string
+		groups []string
+	}{}
+	addUsers := func(policies []accessPolicy, users ...string) {
+		for _, policy := range policies {
+			grant := grants[policy]
+			grant.users = append(grant.users, users...)
+			grants[policy] = grant
+		}
+	}
+	if adminIdentity := a.GetInitialAdminIdentity(); adminIdentity != "" {
+		addUsers(adminPolicies, adminIdentity)
+	}
+	addUsers(nodePolicies, nodeIdentities...)
+	for policy, groups := range getGroupPolicies(a.GetOidcGroups()) {
+		grant := grants[policy]
+		grant.groups = groups
+		grants[policy] = grant
+	}
+
+	policies := make([]accessPolicy, 0, len(grants))
+	for policy := range grants {
+		policies = append(policies, policy)
+	}
+	slices.SortFunc(policies, func(x, y accessPolicy) int {
+		return strings.Compare(x.resource+x.action, y.resource+y.action)
+	})
+
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<authorizations>
+	<policies>`)
+	for _, policy := range policies {
+		grant := grants[policy]
+		if len(grant.users)+len(grant.groups) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, `
+		<policy identifier="%s" resource="%s" action="%s">`,
+			getTenantIdentifier(policy.resource+"-"+policy.action), policy.resource, policy.action)
+		for _, group := range grant.groups {
+			fmt.Fprintf(&sb, `
+			<group identifier="%s"/>`, getGroupIdentifier(group))
+		}
+		for _, user := range grant.users {
+			fmt.Fprintf(&sb, `
+			<user identifier="%s"/>`, getTenantIdentifier(user))
+		}
+		sb.WriteString(`
+		</policy>`)
+	}
+	sb.WriteString(`
+	</policies>
+</authorizations>
+`)
+	return util.IndentTab4Spaces(sb.String())
+}
@@ -0,0 +1,161 @@
+package security
+
+import (
+	"encoding/xml"
+	"testing"
+
+	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+type tenantsFile struct {
+	Groups []struct {
+		Identifier string `xml:"identifier,attr"`
+		Name       string `xml:"name,attr"`
+	} `xml:"groups>group"`
+	Users []struct {
+		Identifier string `xml:"identifier,attr"`
+		Identity   string `xml:"identity,attr"`
+	} `xml:"users>user"`
+}
+
+type authorizationsFile struct {
+	Policies []struct {
+		Resource string `xml:"resource,attr"`
+		Action   string `xml:"action,attr"`
+		Groups   []struct {
+			Identifier string `xml:"identifier,attr"`
+		} `xml:"group"`
+		Users []struct {
+			Identifier string `xml:"identifier,attr"`
+		} `xml:"user"`
+	} `xml:"policies>policy"`
+}
+
+func newOidcGroupsAuthentication(groups *nifiv1alpha1.OidcGroupsSpec) *Authentication {
+	return &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{
+		AuthenticatorTypeOIDC: {&oidcAuthenticator{
+			clusterName: "simple-nifi",
+			config:      &authv1alpha1.OidcSpec{ClientCredentialsSecret: "nifi-oidc-client"},
+			groups:      groups,
+			provider:    &authv1alpha1.OIDCProvider{Hostname: "keycloak", PrincipalClaim: "preferred_username"},
+		}},
+	}}
+}
+
+func TestGetTenantIdentifier(t *testing.T) {
+	// java.util.UUID.nameUUIDFromBytes("admin".getBytes(StandardCharsets.UTF_8))
+	if got := getTenantIdentifier("admin"); got != "21232f29-7a57-35a7-8389-4a0e4a801fc3" {
+		t.Errorf("unexpected identifier %s", got)
+	}
+	if getGroupIdentifier("admin") == getTenantIdentifier("admin") {
+		t.Error("expected a group identifier distinct from the user identifier")
+	}
+}
+
+func TestOidcAuthenticator_GroupsClaim(t *testing.T) {
+	auth := newOidcGroupsAuthentication(&nifiv1alpha1.OidcGroupsSpec{})
+	if value, _ := auth.ExtendNifiProperties().Get("nifi.security.user.oidc.claim.groups"); value != DefaultOidcGroupsClaim {
+		t.Errorf("expected the default groups claim, got %q", value)
+	}
+	if auth.HasGroupPolicies() {
+		t.Error("expected no group policies without groups")
+	}
+
+	withoutGroups := newOidcGroupsAuthentication(nil)
+	if _, ok := withoutGroups.ExtendNifiProperties().Get("nifi.security.user.oidc.claim.groups"); ok {
+		t.Error("expected no groups claim without oidcGroups")
+	}
+}
+
+func TestAuthentication_GroupPolicies(t *testing.T) {
+	auth := newOidcGroupsAuthentication(&nifiv1alpha1.OidcGroupsSpec{
+		Claim:    "roles",
+		Admin:    []string{"nifi-admins"},
+		Operator: []string{"nifi-operators", "nifi-admins"},
+		ReadOnly: []string{"nifi-viewers"},
+	})
+	if !auth.HasGroupPolicies() {
+		t.Fatal("expected group policies")
+	}
+	if value, _ := auth.ExtendNifiProperties().Get("nifi.security.user.oidc.claim.groups"); value != "roles" {
+		t.Errorf("expected the roles claim, got %q", value)
+	}
+
+	var tenants tenantsFile
+	if err := xml.Unmarshal([]byte(auth.GetInitialTenants([]string{NodeIdentity})), &tenants); err != nil {
+		t.Fatalf("expected well-formed users: %v", err)
+	}
+	groupNames := map[string]string{}
+	for _, group := range tenants.Groups {
+		groupNames[group.Identifier] = group.Name
+	}
+	if len(groupNames) != 3 {
+		t.Errorf("expected 3 groups, got %v", groupNames)
+	}
+	// The admin groups administer the cluster, no user is the initial admin by default.
+	if len(tenants.Users) != 1 || tenants.Users[0].Identity != NodeIdentity {
+		t.Errorf("expected only the node user, got %+v", tenants.Users)
+	}
+
+	var authorizations authorizationsFile
+	if err := xml.Unmarshal([]byte(auth.GetInitialAuthorizations([]string{NodeIdentity})), &authorizations); err != nil {
+		t.Fatalf("expected well-formed authorizations: %v", err)
+	}
+	granted := map[string][]string{}
+	for _, policy := range authorizations.Policies {
+		key := policy.Resource + ":" + policy.Action
+		for _, group := range policy.Groups {
+			granted[key] = append(granted[key], groupNames[group.Identifier])
+		}
+		if len(policy.Users) == 0 && len(policy.Groups) == 0 {
+			t.Errorf("expected no empty policy, got %s", key)
+		}
+	}
+
+	for key, expected := range map[string][]string{
+		"/flow:R":     {"nifi-admins", "nifi-operators", "nifi-viewers"},
+		"/counters:W": {"nifi-admins", "nifi-operators"},
+		"/tenants:W":  {"nifi-admins"},
+		"/proxy:W":    nil,
+	} {
+		if len(granted[key]) != len(expected) {
+			t.Errorf("expected %s granted to %v, got %v", key, expected, granted[key])
+			continue
+		}
+		for i := range expected {
+			if granted[key][i] != expected[i] {
+				t.Errorf("expected %s granted to %v, got %v", key, expected, granted[key])
+			}
+		}
+	}
+}
+
+func TestAuthentication_GroupPoliciesInitialAdmin(t *testing.T) {
+	auth := newOidcGroupsAuthentication(&nifiv1alpha1.OidcGroupsSpec{Admin: []string{"nifi-admins"}})
+	auth.Authenticators[AuthenticatorTypeOIDC][0].(*oidcAuthenticator).initialAdmin = "alice"
+
+	var tenants tenantsFile
+	if err := xml.Unmarshal([]byte(auth.GetInitialTenants([]string{NodeIdentity})), &tenants); err != nil {
+		t.Fatalf("expected well-formed users: %v", err)
+	}
+	if len(tenants.Users) != 2 || tenants.Users[0].Identity != "alice" || tenants.Users[1].Identity != NodeIdentity {
+		t.Errorf("expected the initial admin and the node users, got %+v", tenants.Users)
+	}
+
+	var authorizations authorizationsFile
+	if err := xml.Unmarshal([]byte(auth.GetInitialAuthorizations([]string{NodeIdentity})), &authorizations); err != nil {
+		t.Fatalf("expected well-formed authorizations: %v", err)
+	}
+	for _, policy := range authorizations.Policies {
+		if policy.Resource != "/tenants" || policy.Action != "W" {
+			continue
+		}
+		if len(policy.Users) != 1 || policy.Users[0].Identifier != getTenantIdentifier("alice") {
+			t.Errorf("expected /tenants:W granted to alice, got %+v", policy.Users)
+		}
+		return
+	}
+	t.Error("expected the /tenants:W policy")
+}
@@ -0,0 +1,98 @@
+package security
+
+import (
+	"path"
+
+	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/builder"
+	"github.com/zncdatadev/operator-go/pkg/config/properties"
+	"github.com/zncdatadev/operator-go/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	KerberosVolumeName = "kerberos"
+
+	// kerberosServiceName is the service of the SPNEGO principal, `HTTP/<pod address>@<realm>`.
+	kerberosServiceName              = "HTTP"
+	kerberosAuthenticationExpiration = "12 hours"
+)
+
+var _ Authenticator = &kerberosAuthenticator{}
+
+// kerberosAuthenticator authenticates users with SPNEGO and with their Kerberos password. The secret-operator
+// provides the keytab of the pod and the krb5.conf of the realm of the kerberos SecretClass.
+type kerberosAuthenticator struct {
+	clusterName string
+	// initialAdmin is the principal of the initial admin.
+	initialAdmin string
+	provider     *authv1alpha1.KerberosProvider
+}
+
+func getKeytabPath() string {
+	return path.Join(constants.KubedoopKerberosDir, "keytab")
+}
+
+func getKrb5ConfPath() string {
+	return path.Join(constants.KubedoopKerberosDir, "krb5.conf")
+}
+
+func (a *kerberosAuthenticator) GetVolumes() []corev1.Volume {
+	b := builder.NewSecretOperatorVolume(KerberosVolumeName, a.provider.KerberosStorageClass)
+	b.SetFormatName(constants.Kerberos)
+	b.SetScope(&builder.SecretVolumeScope{Pod: true})
+	b.SetKerberosServiceNames(kerberosServiceName)
+
+	return []corev1.Volume{*b.Builde()}
+}
+
+func (a *kerberosAuthenticator) GetVolumeMounts() []corev1.VolumeMount {
+	return []corev1.VolumeMount{
+		{
+			Name:      KerberosVolumeName,
+			MountPath: constants.KubedoopKerberosDir,
+		},
+	}
+}
+
+func (a *kerberosAuthenticator) GetEnvVars() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  "KRB5_CONFIG",
+			Value: getKrb5ConfPath(),
+		},
+	}
+}
+
+// GetInitArgs exports the realm of krb5.conf, the SPNEGO principal and the login identity provider use it.
+func (a *kerberosAuthenticator) GetInitArgs() string {
+	args := `
+export KERBEROS_REALM="$(sed -n 's/^[[:space:]]*default_realm[[:space:]]*=[[:space:]]*//p' ` + getKrb5ConfPath() + ` | head -n 1)"
+	`
+
+	return args
+}
+
+func (a *kerberosAuthenticator) ExtendNifiProperties() *properties.Properties {
+	cfg := properties.NewProperties()
+	cfg.Add("nifi.kerberos.krb5.file", getKrb5ConfPath())
+	cfg.Add("nifi.kerberos.spnego.principal", kerberosServiceName+`/{{ getenv "NODE_ADDRESS" }}@{{ getenv "KERBEROS_REALM" }}`)
+	cfg.Add("nifi.kerberos.spnego.keytab.location", getKeytabPath())
+	cfg.Add("nifi.kerberos.spnego.authentication.expiration", kerberosAuthenticationExpiration)
+	return cfg
+}
+
+// GetInitialAdminIdentity returns the configured initial admin, the users are the principals of the realm.
+func (a *kerberosAuthenticator) GetInitialAdminIdentity() string {
+	return a.initialAdmin
+}
+
+func (a *kerberosAuthenticator) GetLoginIdentiryProvider(identifier string) string {
+	return `
+	<provider>
+		<identifier>` + identifier + `</identifier>
+		<class>org.apache.nifi.kerberos.KerberosProvider</class>
+		<property name="Default Realm">{{ getenv "KERBEROS_REALM" }}</property>
+		<property name="Authentication Expiration">` + kerberosAuthenticationExpiration + `</property>
+	</provider>`
+}
@@ -0,0 +1,67 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/constants"
+)
+
+func newKerberosAuthenticator() *kerberosAuthenticator {
+	return &kerberosAuthenticator{clusterName: "simple-nifi", provider: &authv1alpha1.KerberosProvider{KerberosStorageClass: "kerberos"}}
+}
+
+func TestKerberosAuthenticator_Volumes(t *testing.T) {
+	volumes := newKerberosAuthenticator().GetVolumes()
+	if len(volumes) != 1 || volumes[0].Ephemeral == nil {
+		t.Fatalf("expected a secret-operator ephemeral volume, got %+v", volumes)
+	}
+
+	annotations := volumes[0].Ephemeral.VolumeClaimTemplate.Annotations
+	for key, expected := range map[string]string{
+		constants.AnnotationSecretsClass:                "kerberos",
+		constants.AnnotationSecretsFormat:               string(constants.Kerberos),
+		constants.AnnotationSecretsKerberosServiceNames: "HTTP",
+	} {
+		if annotations[key] != expected {
+			t.Errorf("expected annotation %s=%s, got %q", key, expected, annotations[key])
+		}
+	}
+}
+
+func TestKerberosAuthenticator_NifiProperties(t *testing.T) {
+	props := newKerberosAuthenticator().ExtendNifiProperties()
+
+	for key, expected := range map[string]string{
+		"nifi.kerberos.krb5.file":              "/kubedoop/kerberos/krb5.conf",
+		"nifi.kerberos.spnego.keytab.location": "/kubedoop/kerberos/keytab",
+		"nifi.kerberos.spnego.principal":       `HTTP/{{ getenv "NODE_ADDRESS" }}@{{ getenv "KERBEROS_REALM" }}`,
+	} {
+		if value, _ := props.Get(key); value != expected {
+			t.Errorf("expected %s=%s, got %q", key, expected, value)
+		}
+	}
+}
+
+func TestKerberosAuthenticator_LoginIdentityProvider(t *testing.T) {
+	a := newKerberosAuthenticator()
+
+	if got := a.GetInitArgs(); !strings.Contains(got, "export KERBEROS_REALM=") || !strings.Contains(got, "/kubedoop/kerberos/krb5.conf") {
+		t.Errorf("expected the realm to be read from krb5.conf, got %q", got)
+	}
+
+	got := a.GetLoginIdentiryProvider("login-identity-provider")
+	for _, expected := range []string{
+		"<class>org.apache.nifi.kerberos.KerberosProvider</class>",
+		`<property name="Default Realm">{{ getenv "KERBEROS_REALM" }}</property>`,
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+
+	if err := validateAuthenticators(map[AuthenticatorType][]Authenticator{AuthenticatorKerberos: {a, a}}, ""); err == nil {
+		t.Error("expected an error for two kerberos authenticators")
+	}
+}
@@ -4,24 +4,43 @@ import (
 	"fmt"
 	"path"
 	"strconv"
+	"time"
 
 	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/builder"
 	"github.com/zncdatadev/operator-go/pkg/config/properties"
 	"github.com/zncdatadev/operator-go/pkg/constants"
 	corev1 "k8s.io/api/core/v1"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
 )
 
 const (
-	DefaultServerTlsStorePassword = "changeit"
-	DefaultServerTlsKeyPassword   = "changeit"
+	ldapPort  = 389
+	ldapsPort = 636
+
+	defaultLdapGroupObjectClass     = "groupOfNames"
+	defaultLdapGroupMemberAttribute = "member"
+	defaultLdapSyncInterval         = "30m"
 )
 
 var _ Authenticator = &ldapAuthenticator{}
 
 type ldapAuthenticator struct {
 	clusterName string
-	provider    *authv1alpha1.LDAPProvider
+	groups      *nifiv1alpha1.LdapGroupsSpec
+	// initialAdmin is the DN of the initial admin, the bind user when empty.
+	initialAdmin string
+	// startTls negotiates TLS on the plain LDAP connection instead of LDAP over TLS.
+	startTls bool
+	provider *authv1alpha1.LDAPProvider
+	// storePassword encrypts the truststore of the CA, see GetStorePassword.
+	storePassword string
+}
+
+// hasBindCredentials reports whether the provider binds with credentials, it binds anonymously otherwise.
+func (a *ldapAuthenticator) hasBindCredentials() bool {
+	return a.provider.BindCredentials != nil && a.provider.BindCredentials.SecretClass != ""
 }
 
 func (a *ldapAuthenticator) getBindCredentialsVolumeName() string {
@@ -32,44 +51,114 @@ func (a *ldapAuthenticator) getBindCredentialsMountDir() string {
 	return path.Join(constants.KubedoopSecretDir, a.provider.BindCredentials.SecretClass)
 }
 
-func (a *ldapAuthenticator) GetVolumes() []corev1.Volume {
-	secretClass := a.provider.BindCredentials.SecretClass
-
-	svcScope := make([]string, 0)
-	podScope := false
-	nodeScope := false
-	if a.provider.BindCredentials.Scope != nil {
-		if a.provider.BindCredentials.Scope.Pod {
-			podScope = true
-		}
-		if a.provider.BindCredentials.Scope.Node {
-			nodeScope = true
+func (a *ldapAuthenticator) getCASecretClass() string {
+	if a.provider.TLS == nil {
+		return ""
+	}
+	return getCASecretClass(a.provider.TLS.Verification)
+}
+
+func (a *ldapAuthenticator) getTlsVolumeName() string {
+	return fmt.Sprintf("%s-ldap-tls", a.getCASecretClass())
+}
+
+// getTlsMountDir returns the directory of the truststore holding the CA of the LDAP server.
+func (a *ldapAuthenticator) getTlsMountDir() string {
+	return path.Join(constants.KubedoopTlsDir, "ldap", a.getCASecretClass())
+}
+
+// getPort returns the port of the provider, 636 with LDAP over TLS and 389 otherwise when it is not set.
+func (a *ldapAuthenticator) getPort() int {
+	if a.provider.Port != 0 {
+		return a.provider.Port
+	}
+	if a.provider.TLS != nil && !a.startTls {
+		return ldapsPort
+	}
+	return ldapPort
+}
+
+// useStartTLS reports whether TLS is negotiated on a plain LDAP connection rather than LDAP over TLS.
+func (a *ldapAuthenticator) useStartTLS() bool {
+	return a.provider.TLS != nil && a.startTls
+}
+
+// validateLDAPProvider rejects a provider with TLS but without verification, NiFi always verifies the server,
+// and START_TLS without TLS.
+func validateLDAPProvider(authClass string, provider *authv1alpha1.LDAPProvider, startTls bool) error {
+	if provider.TLS == nil {
+		if startTls {
+			return fmt.Errorf("ldap authentication class %s has no TLS, ldapStartTls is not supported", authClass)
 		}
-		if a.provider.BindCredentials.Scope.Services != nil {
-			for _, s := range a.provider.BindCredentials.Scope.Services {
-				svcScope = append(svcScope, string(constants.ServiceScope)+"="+s)
+		return nil
+	}
+	if err := validateTLSVerification(provider.TLS.Verification); err != nil {
+		return fmt.Errorf("ldap authentication class %s %w", authClass, err)
+	}
+	return nil
+}
+
+func (a *ldapAuthenticator) GetVolumes() []corev1.Volume {
+	volumes := []corev1.Volume{}
+
+	if a.hasBindCredentials() {
+		svcScope := make([]string, 0)
+		podScope := false
+		nodeScope := false
+		if a.provider.BindCredentials.Scope != nil {
+			if a.provider.BindCredentials.Scope.Pod {
+				podScope = true
+			}
+			if a.provider.BindCredentials.Scope.Node {
+				nodeScope = true
+			}
+			if a.provider.BindCredentials.Scope.Services != nil {
+				for _, s := range a.provider.BindCredentials.Scope.Services {
+					svcScope = append(svcScope, string(constants.ServiceScope)+"="+s)
+				}
 			}
 		}
+
+		b := builder.NewSecretOperatorVolume(a.getBindCredentialsVolumeName(), a.provider.BindCredentials.SecretClass)
+		b.SetScope(&builder.SecretVolumeScope{
+			Pod:     podScope,
+			Node:    nodeScope,
+			Service: svcScope,
+		})
+		volumes = append(volumes, *b.Builde())
 	}
 
-	b := builder.NewSecretOperatorVolume(a.getBindCredentialsVolumeName(), secretClass)
-	b.SetScope(&builder.SecretVolumeScope{
-		Pod:     podScope,
-		Node:    nodeScope,
-		Service: svcScope,
-	})
+	if caSecretClass := a.getCASecretClass(); caSecretClass != "" {
+		tls := builder.NewSecretOperatorVolume(a.getTlsVolumeName(), caSecretClass)
+		tls.SetFormatName(constants.TLSP12)
+		tls.SetScope(&builder.SecretVolumeScope{Pod: true})
+		// The password is exposed in the volume annotation, see StorePasswordSecretName.
+		tls.SetPKCS12Password(a.storePassword)
+		volumes = append(volumes, *tls.Builde())
+	}
 
-	return []corev1.Volume{*b.Builde()}
+	return volumes
 }
 
 func (a *ldapAuthenticator) GetVolumeMounts() []corev1.VolumeMount {
+	volumeMounts := []corev1.VolumeMount{}
 
-	return []corev1.VolumeMount{
-		{
+	if a.hasBindCredentials() {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
 			Name:      a.getBindCredentialsVolumeName(),
 			MountPath: a.getBindCredentialsMountDir(),
-		},
+		})
 	}
+
+	if a.getCASecretClass() != "" {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      a.getTlsVolumeName(),
+			MountPath: a.getTlsMountDir(),
+			ReadOnly:  true,
+		})
+	}
+
+	return volumeMounts
 }
 
 func (a *ldapAuthenticator) ExtendNifiProperties() *properties.Properties {
@@ -85,67 +174,167 @@ func (a *ldapAuthenticator) GetInitArgs() string {
 }
 
 func (a *ldapAuthenticator) getBindCredentialsMountPaths() (usernameFile, passwordFile string) {
-	if a.provider.BindCredentials != nil && a.provider.BindCredentials.SecretClass != "" {
+	if a.hasBindCredentials() {
 		usernameFile = path.Join(a.getBindCredentialsMountDir(), "username")
 		passwordFile = path.Join(a.getBindCredentialsMountDir(), "password")
 	}
 	return
 }
 
-func (a *ldapAuthenticator) GetLoginIdentiryProvider() string {
+// GetInitialAdminIdentity returns the configured initial admin, the LDAP provider identifies users by their DN.
+// It falls back to the DN of the bind user, read from the bind credentials when the pod starts. Without
+// bind credentials there is no fallback.
+func (a *ldapAuthenticator) GetInitialAdminIdentity() string {
+	if a.initialAdmin != "" {
+		return a.initialAdmin
+	}
+	usernameFile, _ := a.getBindCredentialsMountPaths()
+	if usernameFile == "" {
+		return ""
+	}
+	return `{{ file.Read "` + usernameFile + `" | strings.TrimSpace }}`
+}
 
+// getConnectionProperties returns the properties connecting to the LDAP server, shared by the login identity
+// provider and the user group provider. The bind credentials are read when the pod starts.
+func (a *ldapAuthenticator) getConnectionProperties() string {
 	authStrategy := "ANONYMOUS"
 	usernameFile, passwordFile := a.getBindCredentialsMountPaths()
 
+	managerDN, managerPassword := "", ""
 	if usernameFile != "" && passwordFile != "" {
 		authStrategy = "SIMPLE"
-		if a.provider.TLS != nil && a.provider.TLS.Verification != nil {
-			authStrategy = "LDAPS"
-		}
+		managerDN = `{{ file.Read "` + usernameFile + `" | strings.TrimSpace }}`
+		managerPassword = `{{ file.Read "` + passwordFile + `" | strings.TrimSpace }}`
 	}
 
 	protocol := "ldap"
-	if a.provider.TLS != nil && a.provider.TLS.Verification != nil {
-		protocol = "ldaps"
+	if a.provider.TLS != nil {
+		authStrategy = "LDAPS"
+		if a.useStartTLS() {
+			authStrategy = "START_TLS"
+		} else {
+			protocol = "ldaps"
+		}
 	}
 
-	searchFilter := a.provider.SearchFilter
-
-	if searchFilter == "" {
-		uidField := a.provider.LDAPFieldNames.Uid
-		searchFilter = fmt.Sprintf("%s={0}", uidField)
+	// NiFi does not present a client certificate, the truststore verifies the server. Without truststore, e.g. with
+	// webPki, the well-known CAs of the JDK verify the server.
+	truststore, truststorePassword, truststoreType := "", "", ""
+	if a.getCASecretClass() != "" {
+		truststore = path.Join(a.getTlsMountDir(), "truststore.p12")
+		truststorePassword = StorePasswordTemplate
+		truststoreType = "PKCS12"
 	}
 
-	ldapProvider := `
-	<provider>
-		<identifier>login-identity-provider</identifier>
-		<class>org.apache.nifi.ldap.LdapProvider</class>
+	return `
 		<property name="Authentication Strategy">` + authStrategy + `</property>
 
-		<property name="Manager DN">${{file:UTF-8:` + usernameFile + `}}</property>
-		<property name="Manager Password">${{file:UTF-8:` + passwordFile + `}</property>
+		<property name="Manager DN">` + managerDN + `</property>
+		<property name="Manager Password">` + managerPassword + `</property>
 
 		<property name="Referral Strategy">THROW</property>
 		<property name="Connect Timeout">10 secs</property>
 		<property name="Read Timeout">10 secs</property>
-		<property name="Url">` + protocol + `://` + a.provider.Hostname + `:` + strconv.Itoa(a.provider.Port) + `</property>
-		<property name="User Search Base">` + a.provider.SearchBase + `</property>
-		<property name="User Search Filter">` + searchFilter + `</property>
+		<property name="Url">` + protocol + `://` + a.provider.Hostname + `:` + strconv.Itoa(a.getPort()) + `</property>
 
 		<property name="TLS - Client Auth">NONE</property>
-		<property name="TLS - Keystore">` + path.Join(constants.KubedoopTlsDir, "ldap", "keystore.p12") + `</property>
-		<property name="TLS - Keystore Password">` + DefaultServerTlsKeyPassword + `</property>
-		<property name="TLS - Keystore Type">PKCS12</property>
-		<property name="TLS - Truststore">` + path.Join(constants.KubedoopTlsDir, "ldap", "truststore.p12") + `</property>
-		<property name="TLS - Truststore Password">` + DefaultServerTlsStorePassword + `</property>
-		<property name="TLS - Truststore Type">PKCS12</property>
+		<property name="TLS - Keystore"></property>
+		<property name="TLS - Keystore Password"></property>
+		<property name="TLS - Keystore Type"></property>
+		<property name="TLS - Truststore">` + truststore + `</property>
+		<property name="TLS - Truststore Password">` + truststorePassword + `</property>
+		<property name="TLS - Truststore Type">` + truststoreType + `</property>
 		<property name="TLS - Protocol">TLSv1.2</property>
 		<property name="TLS - Shutdown Gracefully">true</property>
+`
+}
+
+func (a *ldapAuthenticator) GetLoginIdentiryProvider(identifier string) string {
+	searchFilter := a.provider.SearchFilter
+
+	if searchFilter == "" {
+		// The API server defaults the field names, they are only missing when the class was not admitted by it.
+		uidField := "uid"
+		if a.provider.LDAPFieldNames != nil && a.provider.LDAPFieldNames.Uid != "" {
+			uidField = a.provider.LDAPFieldNames.Uid
+		}
+		searchFilter = fmt.Sprintf("%s={0}", uidField)
+	}
+
+	ldapProvider := `
+	<provider>
+		<identifier>` + identifier + `</identifier>
+		<class>org.apache.nifi.ldap.LdapProvider</class>` + a.getConnectionProperties() + `
+		<property name="User Search Base">` + a.provider.SearchBase + `</property>
+		<property name="User Search Filter">` + searchFilter + `</property>
 
 		<property name="Identity Strategy">USE_DN</property>
 		<property name="Authentication Expiration">7 days</property>
-	</provider>
-	`
+	</provider>`
+
+	return ldapProvider
+}
+
+// GetLdapSyncInterval returns the sync interval of the LDAP user group provider in the NiFi time period format.
+func GetLdapSyncInterval(groups *nifiv1alpha1.LdapGroupsSpec) (string, error) {
+	syncInterval := defaultLdapSyncInterval
+	if groups != nil && groups.SyncInterval != "" {
+		syncInterval = groups.SyncInterval
+	}
+	d, err := time.ParseDuration(syncInterval)
+	if err != nil {
+		return "", fmt.Errorf("invalid LDAP sync interval %q: %w", syncInterval, err)
+	}
+	// NiFi refuses a sync interval under 10 seconds.
+	if d < 10*time.Second {
+		return "", fmt.Errorf("LDAP sync interval %q is shorter than 10s", syncInterval)
+	}
+	return fmt.Sprintf("%d secs", int64(d.Seconds())), nil
+}
+
+// getUserGroupProvider returns the ldap-user-group-provider syncing the users and groups of the search bases.
+// The users are identified by their DN, like the users logged in with the login identity provider.
+func (a *ldapAuthenticator) getUserGroupProvider(identifier string) string {
+	groupSearchBase, groupObjectClass, groupMemberAttribute := "", defaultLdapGroupObjectClass, defaultLdapGroupMemberAttribute
+	if a.groups != nil {
+		groupSearchBase = a.groups.SearchBase
+		if a.groups.ObjectClass != "" {
+			groupObjectClass = a.groups.ObjectClass
+		}
+		if a.groups.MemberAttribute != "" {
+			groupMemberAttribute = a.groups.MemberAttribute
+		}
+	}
+	userGroupNameAttribute := ""
+	if a.provider.LDAPFieldNames != nil {
+		userGroupNameAttribute = a.provider.LDAPFieldNames.Group
+	}
+	// The sync interval is validated when the authentication is created.
+	syncInterval, _ := GetLdapSyncInterval(a.groups)
+
+	return `
+	<userGroupProvider>
+		<identifier>` + identifier + `</identifier>
+		<class>org.apache.nifi.ldap.tenants.LdapUserGroupProvider</class>` + a.getConnectionProperties() + `
+		<property name="Page Size"></property>
+		<property name="Sync Interval">` + syncInterval + `</property>
+		<property name="Group Membership - Enforce Case Sensitivity">false</property>
+
+		<property name="User Search Base">` + a.provider.SearchBase + `</property>
+		<property name="User Object Class">person</property>
+		<property name="User Search Scope">SUBTREE</property>
+		<property name="User Search Filter"></property>
+		<property name="User Identity Attribute"></property>
+		<property name="User Group Name Attribute">` + userGroupNameAttribute + `</property>
+		<property name="User Group Name Attribute - Referenced Group Attribute"></property>
 
-	return getIdentityProvider(ldapProvider)
+		<property name="Group Search Base">` + groupSearchBase + `</property>
+		<property name="Group Object Class">` + groupObjectClass + `</property>
+		<property name="Group Search Scope">SUBTREE</property>
+		<property name="Group Search Filter"></property>
+		<property name="Group Name Attribute">cn</property>
+		<property name="Group Member Attribute">` + groupMemberAttribute + `</property>
+		<property name="Group Member Attribute - Referenced User Attribute"></property>
+	</userGroupProvider>`
 }
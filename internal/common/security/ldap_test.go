@@ -0,0 +1,193 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/constants"
+)
+
+func newLDAPTls(secretClass string) *authv1alpha1.LDAPTLS {
+	caCert := &commonsv1alpha1.CACert{SecretClass: secretClass}
+	if secretClass == "" {
+		caCert.WebPki = &commonsv1alpha1.WebPki{}
+	}
+	return &authv1alpha1.LDAPTLS{Verification: &commonsv1alpha1.TLSVerificationSpec{
+		Server: &commonsv1alpha1.ServerVerification{CACert: caCert},
+	}}
+}
+
+func TestLDAPAuthenticator_LoginIdentityProvider(t *testing.T) {
+	tests := []struct {
+		name       string
+		port       int
+		startTls   bool
+		tls        *authv1alpha1.LDAPTLS
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name: "plain",
+			expected: []string{
+				`<property name="Authentication Strategy">SIMPLE</property>`,
+				`<property name="Url">ldap://openldap:389</property>`,
+				`<property name="TLS - Truststore"></property>`,
+			},
+		},
+		{
+			name: "ldaps with a CA SecretClass",
+			tls:  newLDAPTls("tls"),
+			expected: []string{
+				`<property name="Authentication Strategy">LDAPS</property>`,
+				`<property name="Url">ldaps://openldap:636</property>`,
+				`<property name="TLS - Client Auth">NONE</property>`,
+				`<property name="TLS - Truststore">/kubedoop/tls/ldap/tls/truststore.p12</property>`,
+				`<property name="TLS - Truststore Type">PKCS12</property>`,
+				`<property name="TLS - Truststore Password">{{ getenv "NIFI_STORE_PASSWORD" }}</property>`,
+			},
+			unexpected: []string{"keystore.p12"},
+		},
+		{
+			name:     "start tls",
+			port:     1389,
+			startTls: true,
+			tls:      newLDAPTls("tls"),
+			expected: []string{
+				`<property name="Authentication Strategy">START_TLS</property>`,
+				`<property name="Url">ldap://openldap:1389</property>`,
+			},
+		},
+		{
+			name:     "start tls on the default port",
+			startTls: true,
+			tls:      newLDAPTls("tls"),
+			expected: []string{
+				`<property name="Authentication Strategy">START_TLS</property>`,
+				`<property name="Url">ldap://openldap:389</property>`,
+			},
+		},
+		{
+			name: "ldaps on the plain port number",
+			port: 389,
+			tls:  newLDAPTls("tls"),
+			expected: []string{
+				`<property name="Authentication Strategy">LDAPS</property>`,
+				`<property name="Url">ldaps://openldap:389</property>`,
+			},
+		},
+		{
+			name: "ldaps with webPki",
+			port: 1636,
+			tls:  newLDAPTls(""),
+			expected: []string{
+				`<property name="Authentication Strategy">LDAPS</property>`,
+				`<property name="Url">ldaps://openldap:1636</property>`,
+				`<property name="TLS - Truststore"></property>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newLDAPAuthenticator("ldap-bind")
+			a.provider.Port = tt.port
+			a.provider.TLS = tt.tls
+			a.startTls = tt.startTls
+
+			got := a.GetLoginIdentiryProvider(LoginIdentityProviderIdentifier)
+			for _, expected := range tt.expected {
+				if !strings.Contains(got, expected) {
+					t.Errorf("expected %q in:\n%s", expected, got)
+				}
+			}
+			for _, unexpected := range tt.unexpected {
+				if strings.Contains(got, unexpected) {
+					t.Errorf("unexpected %q in:\n%s", unexpected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticator_TruststoreVolume(t *testing.T) {
+	a := newLDAPAuthenticator("ldap-bind")
+	a.provider.TLS = newLDAPTls("tls")
+	a.storePassword = "secret"
+
+	volumes := a.GetVolumes()
+	if len(volumes) != 2 || volumes[1].Name != "tls-ldap-tls" {
+		t.Fatalf("expected the bind credentials and the truststore volumes, got %+v", volumes)
+	}
+	annotations := volumes[1].Ephemeral.VolumeClaimTemplate.Annotations
+	for key, expected := range map[string]string{
+		constants.AnnotationSecretsClass:          "tls",
+		constants.AnnotationSecretsFormat:         string(constants.TLSP12),
+		constants.AnnotationSecretsPKCS12Password: "secret",
+	} {
+		if annotations[key] != expected {
+			t.Errorf("expected annotation %s=%s, got %q", key, expected, annotations[key])
+		}
+	}
+	if mounts := a.GetVolumeMounts(); len(mounts) != 2 || mounts[1].MountPath != "/kubedoop/tls/ldap/tls" {
+		t.Errorf("expected the truststore mounted in /kubedoop/tls/ldap/tls, got %+v", mounts)
+	}
+
+	a.provider.TLS = newLDAPTls("")
+	if got := len(a.GetVolumes()); got != 1 {
+		t.Errorf("expected no truststore volume with webPki, got %d volumes", got)
+	}
+}
+
+func TestLDAPAuthenticator_AnonymousBind(t *testing.T) {
+	a := &ldapAuthenticator{clusterName: "simple-nifi", provider: &authv1alpha1.LDAPProvider{
+		Hostname:   "openldap",
+		Port:       1389,
+		SearchBase: "ou=users,dc=example,dc=org",
+	}}
+
+	if volumes := a.GetVolumes(); len(volumes) != 0 {
+		t.Errorf("expected no bind credentials volume, got %+v", volumes)
+	}
+	if mounts := a.GetVolumeMounts(); len(mounts) != 0 {
+		t.Errorf("expected no bind credentials mount, got %+v", mounts)
+	}
+	if envVars := a.GetEnvVars(); len(envVars) != 0 {
+		t.Errorf("expected no env vars, got %+v", envVars)
+	}
+	if args := a.GetInitArgs(); args != "" {
+		t.Errorf("expected no init args, got %q", args)
+	}
+
+	provider := a.GetLoginIdentiryProvider(LoginIdentityProviderIdentifier)
+	if !strings.Contains(provider, `<property name="Authentication Strategy">ANONYMOUS</property>`) {
+		t.Errorf("expected the anonymous authentication strategy:\n%s", provider)
+	}
+	if strings.Contains(provider, "bind-credentials") {
+		t.Errorf("expected no bind credentials in the provider:\n%s", provider)
+	}
+
+	a.provider.TLS = newLDAPTls("tls")
+	if volumes := a.GetVolumes(); len(volumes) != 1 || volumes[0].Name != "tls-ldap-tls" {
+		t.Errorf("expected only the truststore volume, got %+v", volumes)
+	}
+}
+
+func TestValidateLDAPProvider(t *testing.T) {
+	none := &authv1alpha1.LDAPTLS{Verification: &commonsv1alpha1.TLSVerificationSpec{None: &commonsv1alpha1.NoneVerification{}}}
+	if err := validateLDAPProvider("openldap", &authv1alpha1.LDAPProvider{TLS: none}, false); err == nil {
+		t.Error("expected an error without server verification")
+	}
+	if err := validateLDAPProvider("openldap", &authv1alpha1.LDAPProvider{}, true); err == nil {
+		t.Error("expected an error for START_TLS without TLS")
+	}
+	for _, provider := range []*authv1alpha1.LDAPProvider{{}, {TLS: newLDAPTls("tls")}, {TLS: newLDAPTls("")}} {
+		if err := validateLDAPProvider("openldap", provider, false); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if err := validateLDAPProvider("openldap", &authv1alpha1.LDAPProvider{TLS: newLDAPTls("tls")}, true); err != nil {
+		t.Errorf("unexpected error for START_TLS: %v", err)
+	}
+}
@@ -7,16 +7,38 @@ import (
 	"strings"
 
 	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/builder"
 	"github.com/zncdatadev/operator-go/pkg/config/properties"
+	"github.com/zncdatadev/operator-go/pkg/constants"
 	corev1 "k8s.io/api/core/v1"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+const (
+	OidcTlsVolumeName        = "oidc-tls"
+	OidcTruststoreVolumeName = "oidc-truststore"
+
+	oidcDiscoveryPath = ".well-known/openid-configuration"
+	oidcCAAlias       = "oidc-ca"
+)
+
+var (
+	// ServerTlsDir holds the keystore and truststore of the NiFi server.
+	ServerTlsDir = path.Join(constants.KubedoopRoot, "nifi", "server-tls")
+
+	oidcCADir         = path.Join(constants.KubedoopTlsDir, "oidc")
+	oidcTruststoreDir = path.Join(constants.KubedoopTlsDir, "oidc-truststore")
 )
 
 var _ Authenticator = &oidcAuthenticator{}
 
 type oidcAuthenticator struct {
-	clusterName string
-	config      *authv1alpha1.OidcSpec
-	provider    *authv1alpha1.OIDCProvider
+	clusterName  string
+	config       *authv1alpha1.OidcSpec
+	groups       *nifiv1alpha1.OidcGroupsSpec
+	initialAdmin string
+	provider     *authv1alpha1.OIDCProvider
 }
 
 func (a *oidcAuthenticator) GetEnvVars() []corev1.EnvVar {
@@ -48,34 +70,81 @@ func (a *oidcAuthenticator) GetEnvVars() []corev1.EnvVar {
 	return envVars
 }
 
+// getCASecretClass returns the SecretClass of the CA verifying the provider, empty without TLS or with the
+// well-known CAs of the JDK.
+func (a *oidcAuthenticator) getCASecretClass() string {
+	if a.provider.TLS == nil {
+		return ""
+	}
+	return getCASecretClass(a.provider.TLS.Verification)
+}
+
+// getOidcTruststorePath returns the truststore merging the NiFi truststore and the CA of the provider.
+func getOidcTruststorePath() string {
+	return path.Join(oidcTruststoreDir, "truststore.p12")
+}
+
+// getDiscoveryURL returns the OpenID Connect discovery document of the provider, https when the provider has TLS.
+func (a *oidcAuthenticator) getDiscoveryURL() string {
+	discovery := url.URL{
+		Scheme: "http",
+		Host:   a.provider.Hostname,
+		Path:   path.Join("/", a.provider.RootPath, oidcDiscoveryPath),
+	}
+	if a.provider.TLS != nil {
+		discovery.Scheme = "https"
+	}
+
+	if a.provider.Port != 0 {
+		discovery.Host = fmt.Sprintf("%s:%d", a.provider.Hostname, a.provider.Port)
+	}
+	return discovery.String()
+}
+
+// validateOIDCProvider rejects a provider with TLS but without verification, NiFi always verifies the provider.
+func validateOIDCProvider(authClass string, provider *authv1alpha1.OIDCProvider) error {
+	if provider.TLS == nil {
+		return nil
+	}
+	if err := validateTLSVerification(provider.TLS.Verification); err != nil {
+		return fmt.Errorf("oidc authentication class %s %w", authClass, err)
+	}
+	return nil
+}
+
 func (a *oidcAuthenticator) GetVolumes() []corev1.Volume {
-	volumes := []corev1.Volume{
-		{
-			Name: NifiAdminUsername,
+	var volumes []corev1.Volume
+
+	if secretClass := a.getCASecretClass(); secretClass != "" {
+		b := builder.NewSecretOperatorVolume(OidcTlsVolumeName, secretClass)
+		b.SetFormatName(constants.TLSPEM)
+		b.SetScope(&builder.SecretVolumeScope{Pod: true})
+		volumes = append(volumes, *b.Builde(), corev1.Volume{
+			Name: OidcTruststoreVolumeName,
 			VolumeSource: corev1.VolumeSource{
-				Secret: &corev1.SecretVolumeSource{
-					SecretName: oidcAdminPasswordSecretname(a.clusterName),
-					Items: []corev1.KeyToPath{
-						{
-							Key:  NifiAdminUsername,
-							Path: NifiAdminUsername,
-						},
-					},
-				},
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
 			},
-		},
+		})
 	}
 
 	return volumes
 }
 
 func (a *oidcAuthenticator) GetVolumeMounts() []corev1.VolumeMount {
-	volumeMounts := []corev1.VolumeMount{
-		{
-			Name:      NifiAdminUsername,
-			MountPath: UserMountDir,
-			ReadOnly:  true,
-		},
+	var volumeMounts []corev1.VolumeMount
+
+	if a.getCASecretClass() != "" {
+		volumeMounts = append(volumeMounts,
+			corev1.VolumeMount{
+				Name:      OidcTlsVolumeName,
+				MountPath: oidcCADir,
+				ReadOnly:  true,
+			},
+			corev1.VolumeMount{
+				Name:      OidcTruststoreVolumeName,
+				MountPath: oidcTruststoreDir,
+			},
+		)
 	}
 
 	return volumeMounts
@@ -87,36 +156,58 @@ func (a *oidcAuthenticator) ExtendNifiProperties() *properties.Properties {
 	scopes = append(scopes, a.config.ExtraScopes...)
 
 	cfg := properties.NewProperties()
-	issuer := url.URL{
-		Scheme: "http",
-		Host:   a.provider.Hostname,
-		Path:   a.provider.RootPath,
-	}
-
-	if a.provider.Port != 0 {
-		issuer.Host = fmt.Sprintf("%s:%d", a.provider.Hostname, a.provider.Port)
-	}
-
-	cfg.Add("nifi.security.user.oidc.discovery.url", issuer.String())
+	cfg.Add("nifi.security.user.oidc.discovery.url", a.getDiscoveryURL())
 	cfg.Add("nifi.security.user.oidc.client.id", `{{ getenv "OIDC_CLIENT_ID" }}`)
 	cfg.Add("nifi.security.user.oidc.client.secret", `{{ getenv "OIDC_CLIENT_SECRET" }}`)
 	cfg.Add("nifi.security.user.oidc.extra.scopes", strings.Join(scopes, ","))
 	cfg.Add("nifi.security.user.oidc.claim.identifying.user", a.provider.PrincipalClaim)
-	// TODO: add oidc tls config
+	if a.groups != nil {
+		claim := a.groups.Claim
+		if claim == "" {
+			claim = DefaultOidcGroupsClaim
+		}
+		cfg.Add("nifi.security.user.oidc.claim.groups", claim)
+	}
+
+	// NIFI verifies the provider with nifi.security.truststore, JDK with the well-known CAs of the JVM.
+	if a.getCASecretClass() != "" {
+		cfg.Add("nifi.security.user.oidc.truststore.strategy", "NIFI")
+		cfg.Add("nifi.security.truststore", getOidcTruststorePath())
+		cfg.Add("nifi.security.truststoreType", "PKCS12")
+		cfg.Add("nifi.security.truststorePasswd", StorePasswordTemplate)
+	} else {
+		cfg.Add("nifi.security.user.oidc.truststore.strategy", "JDK")
+	}
 	return cfg
 }
+
+// GetInitArgs merges, with a CA SecretClass, the CA of the provider into a copy of the server truststore,
+// so NiFi trusts both the cluster and the provider.
 func (a *oidcAuthenticator) GetInitArgs() string {
-	args := `
-export NIFI_ADMIN_PASSWORD="$(python3 -c 'import bcrypt; print(bcrypt.hashpw(open("` + getAdminPasswordMountDir() + `", "rb").read().strip(), bcrypt.gensalt()).decode("utf-8"), end="")')"
+	var args string
+
+	if a.getCASecretClass() != "" {
+		serverTruststore := path.Join(ServerTlsDir, "truststore.p12")
+		args += `
+rm -f ` + getOidcTruststorePath() + `
+if [ -f ` + serverTruststore + ` ]; then
+    cp ` + serverTruststore + ` ` + getOidcTruststorePath() + `
+fi
+keytool -importcert -noprompt -trustcacerts -alias ` + oidcCAAlias + ` -file ` + path.Join(oidcCADir, "ca.crt") + ` -keystore ` + getOidcTruststorePath() + ` -storetype PKCS12 -storepass "$` + StorePasswordEnvName + `"
 	`
+	}
 
 	return args
 }
 
-func (a *oidcAuthenticator) GetLoginIdentiryProvider() string {
-	return getSingleUserLoginIdentityProvider()
+// GetInitialAdminIdentity returns the configured initial admin, OIDC users are identified by the principal
+// claim of the provider. No user of the provider is the initial admin by default.
+func (a *oidcAuthenticator) GetInitialAdminIdentity() string {
+	return a.initialAdmin
 }
 
-func getAdminPasswordMountDir() string {
-	return path.Join(UserMountDir, NifiAdminUsername)
+// GetLoginIdentiryProvider returns no provider, users log in at the OIDC provider. NiFi does not enable
+// OIDC next to a login identity provider.
+func (a *oidcAuthenticator) GetLoginIdentiryProvider(identifier string) string {
+	return ""
 }
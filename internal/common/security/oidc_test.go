@@ -0,0 +1,245 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/constants"
+)
+
+func newOIDCAuthenticator(rootPath string, oidcTls *authv1alpha1.OIDCTls) *oidcAuthenticator {
+	return &oidcAuthenticator{
+		clusterName: "simple-nifi",
+		config:      &authv1alpha1.OidcSpec{ClientCredentialsSecret: "nifi-oidc-client"},
+		provider: &authv1alpha1.OIDCProvider{
+			Hostname:       "keycloak.default.svc.cluster.local",
+			Port:           8443,
+			PrincipalClaim: "preferred_username",
+			ProviderHint:   "keycloak",
+			RootPath:       rootPath,
+			TLS:            oidcTls,
+		},
+	}
+}
+
+func newOIDCTls(secretClass string) *authv1alpha1.OIDCTls {
+	caCert := &commonsv1alpha1.CACert{SecretClass: secretClass}
+	if secretClass == "" {
+		caCert.WebPki = &commonsv1alpha1.WebPki{}
+	}
+	return &authv1alpha1.OIDCTls{Verification: &commonsv1alpha1.TLSVerificationSpec{
+		Server: &commonsv1alpha1.ServerVerification{CACert: caCert},
+	}}
+}
+
+func TestOIDCAuthenticator_DiscoveryOverHTTPS(t *testing.T) {
+	a := newOIDCAuthenticator("/realms/kubedoop", newOIDCTls("oidc-ca"))
+
+	props := a.ExtendNifiProperties()
+	for key, expected := range map[string]string{
+		"nifi.security.user.oidc.discovery.url":       "https://keycloak.default.svc.cluster.local:8443/realms/kubedoop/" + oidcDiscoveryPath,
+		"nifi.security.user.oidc.truststore.strategy": "NIFI",
+		"nifi.security.truststore":                    "/kubedoop/tls/oidc-truststore/truststore.p12",
+		"nifi.security.truststoreType":                "PKCS12",
+		"nifi.security.truststorePasswd":              `{{ getenv "NIFI_STORE_PASSWORD" }}`,
+	} {
+		if value, _ := props.Get(key); value != expected {
+			t.Errorf("expected %s=%s, got %q", key, expected, value)
+		}
+	}
+
+	// The truststore NiFi verifies the provider with is the server truststore plus the CA of the provider.
+	args := a.GetInitArgs()
+	for _, expected := range []string{
+		"cp " + ServerTlsDir + "/truststore.p12 /kubedoop/tls/oidc-truststore/truststore.p12",
+		"keytool -importcert -noprompt -trustcacerts -alias " + oidcCAAlias + " -file /kubedoop/tls/oidc/ca.crt " +
+			`-keystore /kubedoop/tls/oidc-truststore/truststore.p12 -storetype PKCS12 -storepass "$NIFI_STORE_PASSWORD"`,
+	} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("expected %q in:\n%s", expected, args)
+		}
+	}
+
+	plain := newOIDCAuthenticator("/realms/kubedoop", nil)
+	if value, _ := plain.ExtendNifiProperties().Get("nifi.security.user.oidc.discovery.url"); !strings.HasPrefix(value, "http://") {
+		t.Errorf("expected an http discovery URL without TLS, got %q", value)
+	}
+}
+
+// newOIDCDiscoveryStub serves the discovery document of a realm over HTTPS, like keycloak.
+func newOIDCDiscoveryStub(t *testing.T, rootPath string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path.Join(rootPath, oidcDiscoveryPath) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": server.URL + rootPath})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTruststore returns a client trusting the given CAs, like the truststore NiFi verifies the provider with.
+func newTruststore(cas ...*x509.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	for _, ca := range cas {
+		pool.AddCert(ca)
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+}
+
+// newTestCA returns a self-signed CA, standing in for the CA of the NiFi server certificates.
+func newTestCA(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "secret-operator self-signed"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+func TestOIDCAuthenticator_DiscoveryTrust(t *testing.T) {
+	rootPath := "/realms/kubedoop"
+	provider := newOIDCDiscoveryStub(t, rootPath)
+	// The server truststore the OIDC truststore is copied from.
+	clusterCA := newTestCA(t)
+
+	providerURL, err := url.Parse(provider.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(providerURL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newOIDCAuthenticator(rootPath, newOIDCTls("oidc-ca"))
+	a.provider.Hostname = providerURL.Hostname()
+	a.provider.Port = port
+
+	discoveryURL, _ := a.ExtendNifiProperties().Get("nifi.security.user.oidc.discovery.url")
+
+	// The server truststore alone does not trust the provider.
+	if resp, err := newTruststore(clusterCA).Get(discoveryURL); err == nil {
+		_ = resp.Body.Close()
+		t.Fatalf("expected %s to be untrusted without the CA of the provider", discoveryURL)
+	}
+
+	// The init args import the CA of the provider into the copy of the server truststore.
+	resp, err := newTruststore(clusterCA, provider.Certificate()).Get(discoveryURL)
+	if err != nil {
+		t.Fatalf("failed to fetch %s with the merged truststore: %v", discoveryURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the discovery document at %s, got %s", discoveryURL, resp.Status)
+	}
+	var document map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		t.Fatal(err)
+	}
+	if document["issuer"] != provider.URL+rootPath {
+		t.Errorf("expected issuer %s, got %s", provider.URL+rootPath, document["issuer"])
+	}
+}
+
+func TestOIDCAuthenticator_CAVolume(t *testing.T) {
+	a := newOIDCAuthenticator("/", newOIDCTls("oidc-ca"))
+
+	var caVolumeFound bool
+	for _, volume := range a.GetVolumes() {
+		if volume.Name != OidcTlsVolumeName {
+			continue
+		}
+		caVolumeFound = true
+		annotations := volume.Ephemeral.VolumeClaimTemplate.Annotations
+		if annotations[constants.AnnotationSecretsClass] != "oidc-ca" || annotations[constants.AnnotationSecretsFormat] != string(constants.TLSPEM) {
+			t.Errorf("expected a tls-pem volume of the oidc-ca SecretClass, got %v", annotations)
+		}
+	}
+	if !caVolumeFound {
+		t.Errorf("expected the %s volume", OidcTlsVolumeName)
+	}
+	if got := a.GetInitArgs(); !strings.Contains(got, "keytool -importcert") || !strings.Contains(got, "/kubedoop/tls/oidc/ca.crt") ||
+		!strings.Contains(got, `-storepass "$NIFI_STORE_PASSWORD"`) {
+		t.Errorf("expected the CA to be merged into the truststore, got %q", got)
+	}
+
+	webPki := newOIDCAuthenticator("/", newOIDCTls(""))
+	if got := len(webPki.GetVolumes()); got != 0 {
+		t.Errorf("expected no CA volume with webPki, got %d volumes", got)
+	}
+	if value, _ := webPki.ExtendNifiProperties().Get("nifi.security.user.oidc.truststore.strategy"); value != "JDK" {
+		t.Errorf("expected the JDK truststore with webPki, got %q", value)
+	}
+	if value, _ := webPki.ExtendNifiProperties().Get("nifi.security.user.oidc.discovery.url"); value != "https://keycloak.default.svc.cluster.local:8443/"+oidcDiscoveryPath {
+		t.Errorf("unexpected discovery URL %q", value)
+	}
+}
+
+func TestValidateOIDCProvider(t *testing.T) {
+	if err := validateOIDCProvider("keycloak", &authv1alpha1.OIDCProvider{}); err != nil {
+		t.Errorf("unexpected error without TLS: %v", err)
+	}
+	none := &authv1alpha1.OIDCTls{Verification: &commonsv1alpha1.TLSVerificationSpec{None: &commonsv1alpha1.NoneVerification{}}}
+	if err := validateOIDCProvider("keycloak", &authv1alpha1.OIDCProvider{TLS: none}); err == nil {
+		t.Error("expected an error without server verification")
+	}
+	if err := validateOIDCProvider("keycloak", &authv1alpha1.OIDCProvider{TLS: newOIDCTls("oidc-ca")}); err != nil {
+		t.Errorf("unexpected error with a CA SecretClass: %v", err)
+	}
+	// A `configMap` CA is pruned by the AuthenticationClass schema, leaving an empty caCert.
+	empty := &authv1alpha1.OIDCTls{Verification: &commonsv1alpha1.TLSVerificationSpec{
+		Server: &commonsv1alpha1.ServerVerification{CACert: &commonsv1alpha1.CACert{}},
+	}}
+	if err := validateOIDCProvider("keycloak", &authv1alpha1.OIDCProvider{TLS: empty}); err == nil || !strings.Contains(err.Error(), "ConfigMap") {
+		t.Errorf("expected an error rejecting a ConfigMap CA, got %v", err)
+	}
+}
+
+func TestOIDCAuthenticator_NoLoginIdentityProvider(t *testing.T) {
+	auth := &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{
+		AuthenticatorTypeOIDC: {newOIDCAuthenticator("/", nil)},
+	}}
+	if auth.HasLoginIdentityProvider() {
+		t.Errorf("expected no login identity provider with OIDC, got:\n%s", auth.GetLoginIdentiryProvider())
+	}
+	if got := auth.GetInitArgs(); strings.Contains(got, "NIFI_ADMIN_PASSWORD") {
+		t.Errorf("expected no admin password without login identity provider, got %q", got)
+	}
+}
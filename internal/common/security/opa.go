@@ -0,0 +1,105 @@
+package security
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zncdatadev/operator-go/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+const (
+	// OpaAuthorizerClass is the NiFi authorizer of the nifi-opa-plugin (https://github.com/DavidGitter/nifi-opa-plugin),
+	// the plugin must be installed in the lib directory of the NiFi image.
+	OpaAuthorizerClass = "org.nifi.authorizer.OpaAuthorizer"
+
+	// OpaBaseURLEnvName is set from the `OPA` key of the discovery ConfigMap.
+	OpaBaseURLEnvName = "OPA_BASE_URL"
+	opaDiscoveryKey   = "OPA"
+
+	DefaultOpaPackage              = "nifi"
+	DefaultOpaCacheEntryTimeToLive = 30 * time.Second
+	DefaultOpaCacheMaxEntries      = 10000
+)
+
+// GetOpaEnvVars returns the env vars exposing the discovery ConfigMap of the OPA cluster to NiFi.
+func GetOpaEnvVars(opa *nifiv1alpha1.OpaSpec) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name: OpaBaseURLEnvName,
+			ValueFrom: &corev1.EnvVarSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					Key: opaDiscoveryKey,
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: opa.ConfigMapName,
+					},
+				},
+			},
+		},
+	}
+}
+
+// GetOpaRuleHead returns the path of the `allow` rule of the package below the OPA data API,
+// e.g. `nifi/allow` for the package `nifi`.
+func GetOpaRuleHead(opa *nifiv1alpha1.OpaSpec) string {
+	pkg := opa.Package
+	if pkg == "" {
+		pkg = DefaultOpaPackage
+	}
+	return strings.ReplaceAll(pkg, ".", "/") + "/allow"
+}
+
+// GetOpaCacheConfig returns the time to live in seconds and the maximum number of the cached decisions.
+func GetOpaCacheConfig(opa *nifiv1alpha1.OpaSpec) (timeToLiveSeconds int64, maxEntries int32, err error) {
+	timeToLive := DefaultOpaCacheEntryTimeToLive
+	maxEntries = DefaultOpaCacheMaxEntries
+
+	if opa.Cache != nil {
+		if opa.Cache.EntryTimeToLive != "" {
+			timeToLive, err = time.ParseDuration(opa.Cache.EntryTimeToLive)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid OPA cache entryTimeToLive %q: %w", opa.Cache.EntryTimeToLive, err)
+			}
+			if timeToLive < 0 {
+				return 0, 0, fmt.Errorf("invalid OPA cache entryTimeToLive %q: must not be negative", opa.Cache.EntryTimeToLive)
+			}
+		}
+		if opa.Cache.MaxEntries > 0 {
+			maxEntries = opa.Cache.MaxEntries
+		}
+	}
+
+	return int64(timeToLive / time.Second), maxEntries, nil
+}
+
+// GetOpaAuthorizers returns authorizers.xml delegating every authorization decision to OPA. The base URL
+// is rendered by gomplate from OpaBaseURLEnvName when the pod starts.
+func GetOpaAuthorizers(opa *nifiv1alpha1.OpaSpec) (string, error) {
+	timeToLiveSeconds, maxEntries, err := GetOpaCacheConfig(opa)
+	if err != nil {
+		return "", err
+	}
+
+	authorizer := `
+	<authorizer>
+		<identifier>` + AuthorizerIdentifier + `</identifier>
+		<class>` + OpaAuthorizerClass + `</class>
+		<property name="OPA_URI">{{ getenv "` + OpaBaseURLEnvName + `" }}</property>
+		<property name="OPA_RULE_HEAD">` + GetOpaRuleHead(opa) + `</property>
+		<property name="CACHE_TIME_SECS">` + strconv.FormatInt(timeToLiveSeconds, 10) + `</property>
+		<property name="CACHE_MAX_ENTRY_COUNT">` + strconv.FormatInt(int64(maxEntries), 10) + `</property>
+	</authorizer>`
+
+	snippet := []string{
+		`<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<authorizers>`,
+		authorizer,
+		`</authorizers>
+`,
+	}
+	return util.IndentTab4Spaces(strings.Join(snippet, "\n")), nil
+}
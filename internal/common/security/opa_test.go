@@ -0,0 +1,173 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func TestGetOpaRuleHead(t *testing.T) {
+	for pkg, expected := range map[string]string{
+		"":              "nifi/allow",
+		"nifi":          "nifi/allow",
+		"kubedoop.nifi": "kubedoop/nifi/allow",
+	} {
+		if got := GetOpaRuleHead(&nifiv1alpha1.OpaSpec{ConfigMapName: "opa", Package: pkg}); got != expected {
+			t.Errorf("package %q: expected rule head %s, got %s", pkg, expected, got)
+		}
+	}
+}
+
+func TestGetOpaEnvVars(t *testing.T) {
+	envVars := GetOpaEnvVars(&nifiv1alpha1.OpaSpec{ConfigMapName: "opa"})
+	if len(envVars) != 1 || envVars[0].Name != OpaBaseURLEnvName {
+		t.Fatalf("expected the %s env var, got %v", OpaBaseURLEnvName, envVars)
+	}
+	ref := envVars[0].ValueFrom.ConfigMapKeyRef
+	if ref == nil || ref.Name != "opa" || ref.Key != "OPA" {
+		t.Errorf("expected the OPA key of the discovery ConfigMap opa, got %+v", envVars[0].ValueFrom)
+	}
+}
+
+func TestGetOpaAuthorizers(t *testing.T) {
+	got, err := GetOpaAuthorizers(&nifiv1alpha1.OpaSpec{
+		ConfigMapName: "opa",
+		Package:       "nifi",
+		Cache:         &nifiv1alpha1.OpaCacheSpec{EntryTimeToLive: "2m", MaxEntries: 500},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"<identifier>authorizer</identifier>",
+		"<class>org.nifi.authorizer.OpaAuthorizer</class>",
+		`<property name="OPA_URI">{{ getenv "OPA_BASE_URL" }}</property>`,
+		`<property name="OPA_RULE_HEAD">nifi/allow</property>`,
+		`<property name="CACHE_TIME_SECS">120</property>`,
+		`<property name="CACHE_MAX_ENTRY_COUNT">500</property>`,
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+}
+
+func TestGetOpaCacheConfig(t *testing.T) {
+	timeToLive, maxEntries, err := GetOpaCacheConfig(&nifiv1alpha1.OpaSpec{ConfigMapName: "opa"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if timeToLive != 30 || maxEntries != DefaultOpaCacheMaxEntries {
+		t.Errorf("expected the default cache config, got %d seconds and %d entries", timeToLive, maxEntries)
+	}
+
+	for _, entryTimeToLive := range []string{"soon", "-1m"} {
+		opa := &nifiv1alpha1.OpaSpec{ConfigMapName: "opa", Cache: &nifiv1alpha1.OpaCacheSpec{EntryTimeToLive: entryTimeToLive}}
+		if _, _, err := GetOpaCacheConfig(opa); err == nil {
+			t.Errorf("expected an error for entryTimeToLive %q", entryTimeToLive)
+		}
+	}
+}
+
+// newOpaStandIn returns a server answering the OPA data API like an OPA cluster with the rule
+// `<package>.allow` allowing the `admin` user only.
+func newOpaStandIn(t *testing.T, ruleHead string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/data/"+ruleHead {
+			// OPA answers undefined rules without a result.
+			_ = json.NewEncoder(w).Encode(map[string]any{})
+			return
+		}
+		var request struct {
+			Input struct {
+				Identity string `json:"identity"`
+			} `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": request.Input.Identity == "admin"})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// getOpaAuthorizerProperties returns the properties of the OPA authorizer of authorizers.xml.
+func getOpaAuthorizerProperties(t *testing.T, authorizers string) map[string]string {
+	t.Helper()
+	var document struct {
+		Properties []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"authorizer>property"`
+	}
+	if err := xml.Unmarshal([]byte(authorizers), &document); err != nil {
+		t.Fatalf("expected well-formed authorizers: %v", err)
+	}
+	properties := map[string]string{}
+	for _, property := range document.Properties {
+		properties[property.Name] = property.Value
+	}
+	return properties
+}
+
+// queryOpa queries the rule like the NiFi OPA authorizer and reports whether the rule is defined.
+func queryOpa(t *testing.T, baseURL, ruleHead, identity string) (allowed bool, defined bool) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"input": map[string]string{"identity": identity}})
+	resp, err := http.Post(strings.TrimSuffix(baseURL, "/")+"/v1/data/"+ruleHead, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to query OPA: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var response struct {
+		Result *bool `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode the OPA response: %v", err)
+	}
+	if response.Result == nil {
+		return false, false
+	}
+	return *response.Result, true
+}
+
+func TestGetOpaAuthorizers_Decisions(t *testing.T) {
+	server := newOpaStandIn(t, "kubedoop/nifi/allow")
+
+	authorizers, err := GetOpaAuthorizers(&nifiv1alpha1.OpaSpec{ConfigMapName: "opa", Package: "kubedoop.nifi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	properties := getOpaAuthorizerProperties(t, authorizers)
+	// gomplate renders the base URL from the discovery ConfigMap, which ends with a slash.
+	baseURL := strings.ReplaceAll(properties["OPA_URI"], `{{ getenv "`+OpaBaseURLEnvName+`" }}`, server.URL+"/")
+	ruleHead := properties["OPA_RULE_HEAD"]
+
+	if allowed, defined := queryOpa(t, baseURL, ruleHead, "admin"); !defined || !allowed {
+		t.Errorf("expected admin to be allowed, got allowed=%v defined=%v", allowed, defined)
+	}
+	if allowed, defined := queryOpa(t, baseURL, ruleHead, "alice"); !defined || allowed {
+		t.Errorf("expected alice to be denied, got allowed=%v defined=%v", allowed, defined)
+	}
+
+	// The default package is `nifi`, a rule the stand-in does not define.
+	defaultAuthorizers, err := GetOpaAuthorizers(&nifiv1alpha1.OpaSpec{ConfigMapName: "opa"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defaultRuleHead := getOpaAuthorizerProperties(t, defaultAuthorizers)["OPA_RULE_HEAD"]
+	if _, defined := queryOpa(t, baseURL, defaultRuleHead, "admin"); defined {
+		t.Errorf("expected %s to be undefined", defaultRuleHead)
+	}
+}
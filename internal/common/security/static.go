@@ -1,6 +1,7 @@
 package security
 
 import (
+	"path"
 	"strings"
 
 	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
@@ -64,8 +65,13 @@ export NIFI_ADMIN_PASSWORD="$(python3 -c 'import bcrypt; print(bcrypt.hashpw(ope
 	return args
 }
 
-func (a *staticAuthenticator) GetLoginIdentiryProvider() string {
-	return getSingleUserLoginIdentityProvider()
+// GetInitialAdminIdentity returns the user of the single user login identity provider.
+func (a *staticAuthenticator) GetInitialAdminIdentity() string {
+	return NifiAdminUsername
+}
+
+func (a *staticAuthenticator) GetLoginIdentiryProvider(identifier string) string {
+	return getSingleUserLoginIdentityProvider(identifier)
 }
 
 func getIdentityProvider(provider ...string) string {
@@ -84,13 +90,16 @@ func getIdentityProvider(provider ...string) string {
 	return util.IndentTab4Spaces(strings.Join(snippet, "\n"))
 }
 
-func getSingleUserLoginIdentityProvider() string {
-	provider := `
+func getAdminPasswordMountDir() string {
+	return path.Join(UserMountDir, NifiAdminUsername)
+}
+
+func getSingleUserLoginIdentityProvider(identifier string) string {
+	return `
 	<provider>
-		<identifier>login-identity-provider</identifier>
+		<identifier>` + identifier + `</identifier>
 		<class>org.apache.nifi.authentication.single.user.SingleUserLoginIdentityProvider</class>
 		<property name="Username">admin</property>
 		<property name="Password">{{ getenv "NIFI_ADMIN_PASSWORD" }}</property>
 	</provider>`
-	return getIdentityProvider(provider)
 }
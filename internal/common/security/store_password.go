@@ -0,0 +1,118 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/zncdatadev/operator-go/pkg/builder"
+	"github.com/zncdatadev/operator-go/pkg/client"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// StorePasswordKey is the key of the store password in the store password secret.
+	StorePasswordKey = "storePassword"
+	// StorePasswordEnvName holds the store password in the NiFi containers.
+	StorePasswordEnvName = "NIFI_STORE_PASSWORD"
+	// StorePasswordTemplate is replaced with the store password when gomplate renders the config files.
+	StorePasswordTemplate = `{{ getenv "` + StorePasswordEnvName + `" }}`
+
+	storePasswordLength = 16
+)
+
+var storePasswordLogger = ctrl.Log.WithName("security").WithName("storepassword")
+
+// StorePasswordSecretName returns the secret holding the password of the PKCS12 keystores and truststores of a cluster.
+//
+// The secret-operator cannot reference this secret, it reads the password from the `tlsPKCS12Password` annotation of
+// the volumes, so the password is also stored in cleartext in the StatefulSets and the PersistentVolumeClaims of the
+// ephemeral volumes. Everyone allowed to read StatefulSets or PersistentVolumeClaims in the namespace can read the
+// password, grant these permissions like the permission to read the secret. The OIDC CA is mounted as PEM, its
+// truststore is written by the init args from the env var and does not expose the password.
+func StorePasswordSecretName(clusterName string) string {
+	return clusterName + "-tls-store-password"
+}
+
+// GetStorePassword returns the password of the PKCS12 stores of the cluster, generating the secret when it does not
+// exist yet. The secret-operator encrypts the stores with it, so the secret is kept once created.
+func GetStorePassword(ctx context.Context, client *client.Client, clusterName string) (string, error) {
+	secret := &corev1.Secret{}
+	ns := client.GetOwnerNamespace()
+	name := StorePasswordSecretName(clusterName)
+
+	if err := client.Get(ctx, ctrlclient.ObjectKey{Namespace: ns, Name: name}, secret); err != nil {
+		if ctrlclient.IgnoreNotFound(err) != nil {
+			return "", err
+		}
+
+		storePasswordLogger.Info("Store password secret not found, creating it", "namespace", ns, "name", name)
+		b := NewStorePasswordBuilder(client, clusterName)
+		if _, err := b.Build(ctx); err != nil {
+			return "", err
+		}
+		// When another reconcile created the secret meanwhile, CreateDoesNotExist fills in the existing secret
+		// and its password must be used, the stores are already encrypted with it.
+		secret = b.GetObject()
+		if err := client.CreateDoesNotExist(ctx, secret); err != nil {
+			return "", err
+		}
+	}
+
+	password := secret.Data[StorePasswordKey]
+	if len(password) == 0 {
+		// The API server moves stringData to data, a client that does not convert it keeps the generated password there.
+		password = []byte(secret.StringData[StorePasswordKey])
+	}
+	if len(password) == 0 {
+		return "", fmt.Errorf("store password secret %s/%s does not contain %s", ns, name, StorePasswordKey)
+	}
+	return string(password), nil
+}
+
+// GetStorePasswordEnvVar exposes the store password to the containers, the config files reference it with
+// StorePasswordTemplate.
+func GetStorePasswordEnvVar(clusterName string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: StorePasswordEnvName,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				Key: StorePasswordKey,
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: StorePasswordSecretName(clusterName),
+				},
+			},
+		},
+	}
+}
+
+type StorePasswordBuilder struct {
+	builder.SecretBuilder
+}
+
+func NewStorePasswordBuilder(client *client.Client, clusterName string) *StorePasswordBuilder {
+	return &StorePasswordBuilder{
+		SecretBuilder: *builder.NewSecretBuilder(
+			client,
+			StorePasswordSecretName(clusterName),
+			func(o *builder.Options) {
+				o.ClusterName = clusterName
+			},
+		),
+	}
+}
+
+// Build generates a random hex password, it is written unquoted into properties, XML and shell arguments.
+func (b *StorePasswordBuilder) Build(ctx context.Context) (ctrlclient.Object, error) {
+	randomBytes := make([]byte, storePasswordLength)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, err
+	}
+
+	b.AddItem(StorePasswordKey, hex.EncodeToString(randomBytes))
+
+	return b.GetObject(), nil
+}
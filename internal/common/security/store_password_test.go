@@ -0,0 +1,114 @@
+package security
+
+import (
+	"context"
+	"testing"
+
+	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/client"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func newFakeClient(t *testing.T, objs ...ctrlclient.Object) *client.Client {
+	t.Helper()
+	return newFakeClientWithInterceptor(t, interceptor.Funcs{}, objs...)
+}
+
+func newFakeClientWithInterceptor(t *testing.T, funcs interceptor.Funcs, objs ...ctrlclient.Object) *client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := nifiv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := authv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	owner := &nifiv1alpha1.NifiCluster{ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi", Namespace: "default", UID: "uid"}}
+	return client.NewClient(fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(funcs).Build(), owner)
+}
+
+func TestGetStorePassword(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(t)
+
+	password, err := GetStorePassword(ctx, c, "simple-nifi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(password) != 2*storePasswordLength {
+		t.Errorf("expected a password of %d characters, got %q", 2*storePasswordLength, password)
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, ctrlclient.ObjectKey{Namespace: "default", Name: "simple-nifi-tls-store-password"}, secret); err != nil {
+		t.Fatalf("expected the store password secret: %v", err)
+	}
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Name != "simple-nifi" {
+		t.Errorf("expected the secret owned by the cluster, got %v", secret.OwnerReferences)
+	}
+
+	// The fake client does not convert stringData, the API server does.
+	secret.Data = map[string][]byte{StorePasswordKey: []byte(secret.StringData[StorePasswordKey])}
+	if err := c.GetCtrlClient().Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	again, err := GetStorePassword(ctx, c, "simple-nifi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again != password {
+		t.Errorf("expected the password of the existing secret %q, got %q", password, again)
+	}
+}
+
+func TestGetStorePassword_MissingKey(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient(t)
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "simple-nifi-tls-store-password"}}
+	if err := c.GetCtrlClient().Create(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetStorePassword(ctx, c, "simple-nifi"); err == nil {
+		t.Error("expected an error for a secret without the store password")
+	}
+}
+
+func TestGetStorePassword_CreatedConcurrently(t *testing.T) {
+	ctx := context.Background()
+	existing := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "simple-nifi-tls-store-password"},
+		Data:       map[string][]byte{StorePasswordKey: []byte("existing")},
+	}
+	// The first lookup misses the secret, as if another reconcile created it right after.
+	missed := false
+	c := newFakeClientWithInterceptor(t, interceptor.Funcs{
+		Get: func(ctx context.Context, c ctrlclient.WithWatch, key ctrlclient.ObjectKey, obj ctrlclient.Object, opts ...ctrlclient.GetOption) error {
+			if !missed {
+				missed = true
+				return apierrors.NewNotFound(corev1.Resource("secrets"), key.Name)
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}, existing)
+
+	password, err := GetStorePassword(ctx, c, "simple-nifi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if password != "existing" {
+		t.Errorf("expected the password of the existing secret, got %q", password)
+	}
+}
@@ -0,0 +1,62 @@
+package security
+
+import (
+	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/config/properties"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ClientCertIdentityPattern maps a certificate DN to its common name, e.g. `CN=alice, OU=dev` to `alice`.
+	// NiFi applies the mapping to every identity, including the initial identities of authorizers.xml.
+	ClientCertIdentityPattern = `^CN=([^,]+)(?:,.*)?$`
+	ClientCertIdentityValue   = "$1"
+)
+
+var _ Authenticator = &tlsAuthenticator{}
+
+// tlsAuthenticator authenticates users with client certificates. NiFi trusts the client certificates issued by the
+// CA of its server certificates, so the client certificate SecretClass is the server SecretClass of the cluster.
+//
+// Jetty always asks for a client certificate when TLS is enabled, and requires one when no login identity provider,
+// OIDC included, is configured.
+type tlsAuthenticator struct {
+	clusterName  string
+	initialAdmin string
+	provider     *authv1alpha1.TLSProvider
+}
+
+func (a *tlsAuthenticator) GetVolumes() []corev1.Volume {
+	return nil
+}
+
+func (a *tlsAuthenticator) GetVolumeMounts() []corev1.VolumeMount {
+	return nil
+}
+
+func (a *tlsAuthenticator) GetEnvVars() []corev1.EnvVar {
+	return nil
+}
+
+func (a *tlsAuthenticator) GetInitArgs() string {
+	return ""
+}
+
+func (a *tlsAuthenticator) ExtendNifiProperties() *properties.Properties {
+	cfg := properties.NewProperties()
+	cfg.Add("nifi.security.identity.mapping.pattern.dn", ClientCertIdentityPattern)
+	cfg.Add("nifi.security.identity.mapping.value.dn", ClientCertIdentityValue)
+	cfg.Add("nifi.security.identity.mapping.transform.dn", "NONE")
+	return cfg
+}
+
+// GetInitialAdminIdentity returns the configured initial admin, the common name of its client certificate
+// once the identity mapping is applied. No certificate is the initial admin by default.
+func (a *tlsAuthenticator) GetInitialAdminIdentity() string {
+	return a.initialAdmin
+}
+
+// GetLoginIdentiryProvider returns no provider, the client certificate is checked during the TLS handshake.
+func (a *tlsAuthenticator) GetLoginIdentiryProvider(identifier string) string {
+	return ""
+}
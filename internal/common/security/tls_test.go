@@ -0,0 +1,73 @@
+package security
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	authv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/authentication/v1alpha1"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func TestTLSAuthenticator_IdentityMapping(t *testing.T) {
+	props := (&tlsAuthenticator{clusterName: "simple-nifi", provider: &authv1alpha1.TLSProvider{}}).ExtendNifiProperties()
+
+	pattern, _ := props.Get("nifi.security.identity.mapping.pattern.dn")
+	value, _ := props.Get("nifi.security.identity.mapping.value.dn")
+	re := regexp.MustCompile(pattern)
+	for dn, expected := range map[string]string{
+		"CN=admin":                             "admin",
+		"CN=alice, OU=dev, O=example":          "alice",
+		"CN=generated certificate for pod,O=x": "generated certificate for pod",
+	} {
+		if got := re.ReplaceAllString(dn, value); got != expected {
+			t.Errorf("expected %s to map to %s, got %q", dn, expected, got)
+		}
+	}
+}
+
+func TestAuthentication_ClientCertificatesOnly(t *testing.T) {
+	auth := &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{
+		AuthenticatorTLS: {&tlsAuthenticator{clusterName: "simple-nifi", provider: &authv1alpha1.TLSProvider{}}},
+	}}
+
+	if auth.HasLoginIdentityProvider() {
+		t.Errorf("expected no login identity provider, got:\n%s", auth.GetLoginIdentiryProvider())
+	}
+	// No client certificate is the initial admin by default.
+	if got := auth.GetInitialAdminIdentity(); got != "" {
+		t.Errorf("expected no initial admin, got %q", got)
+	}
+	auth.Authenticators[AuthenticatorTLS][0].(*tlsAuthenticator).initialAdmin = "alice"
+	if got := auth.GetInitialAdminIdentity(); got != "alice" {
+		t.Errorf("expected the configured initial admin alice, got %q", got)
+	}
+
+	withLDAP := &Authentication{Authenticators: map[AuthenticatorType][]Authenticator{
+		AuthenticatorTLS:      auth.Authenticators[AuthenticatorTLS],
+		AuthenticatorTypeLDAP: {newLDAPAuthenticator("ldap-bind")},
+	}}
+	if err := validateAuthenticators(withLDAP.Authenticators, ""); err != nil {
+		t.Errorf("expected client certificates combined with LDAP, got %v", err)
+	}
+	if got := withLDAP.GetLoginIdentiryProvider(); !strings.Contains(got, "<class>org.apache.nifi.ldap.LdapProvider</class>") {
+		t.Errorf("expected the LDAP provider:\n%s", got)
+	}
+}
+
+func TestValidateTLSProvider(t *testing.T) {
+	tls := &nifiv1alpha1.TlsSpec{ServerSecretClass: "tls"}
+
+	if err := validateTLSProvider("mtls", &authv1alpha1.TLSProvider{}, nil); err == nil {
+		t.Error("expected an error without server TLS")
+	}
+	if err := validateTLSProvider("mtls", &authv1alpha1.TLSProvider{ClientCertSecretClass: "other"}, tls); err == nil {
+		t.Error("expected an error for a client certificate SecretClass other than the server SecretClass")
+	}
+	for _, class := range []string{"", "tls"} {
+		if err := validateTLSProvider("mtls", &authv1alpha1.TLSProvider{ClientCertSecretClass: class}, tls); err != nil {
+			t.Errorf("unexpected error for SecretClass %q: %v", class, err)
+		}
+	}
+}
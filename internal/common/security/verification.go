@@ -0,0 +1,27 @@
+package security
+
+import (
+	"errors"
+
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+// getCASecretClass returns the SecretClass of the CA verifying a server, empty without verification or with the
+// well-known CAs of the JDK.
+func getCASecretClass(verification *commonsv1alpha1.TLSVerificationSpec) string {
+	if verification == nil || verification.Server == nil || verification.Server.CACert == nil {
+		return ""
+	}
+	return verification.Server.CACert.SecretClass
+}
+
+// validateTLSVerification rejects TLS without server verification, NiFi always verifies the servers it connects to.
+func validateTLSVerification(verification *commonsv1alpha1.TLSVerificationSpec) error {
+	if verification == nil || verification.Server == nil || verification.Server.CACert == nil {
+		return errors.New("requires the server verification of its TLS, NiFi cannot skip it")
+	}
+	if verification.Server.CACert.SecretClass == "" && verification.Server.CACert.WebPki == nil {
+		return errors.New("requires a CA SecretClass or webPki, a CA from a ConfigMap is not supported")
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -25,6 +26,11 @@ var (
 	_ reconciler.Reconciler = &Reconciler{}
 )
 
+// ErrZooKeeperRequired is returned when a NiFi 1.x cluster is configured without ZooKeeper.
+// NiFi 1.x does not ship the KubernetesConfigMapStateProvider and KubernetesLeaderElectionManager
+// used in Kubernetes-native clustering mode, its pods would crash-loop.
+var ErrZooKeeperRequired = errors.New("NiFi 1.x does not support Kubernetes-native clustering, zookeeperConfigMapName is required")
+
 type Reconciler struct {
 	reconciler.BaseCluster[*nifiv1alpha1.NifiClusterSpec]
 	ClusterConfig *nifiv1alpha1.ClusterConfigSpec
@@ -74,6 +80,14 @@ func (r *Reconciler) GetImage() *util.Image {
 
 func (r *Reconciler) RegisterResources(ctx context.Context) error {
 
+	// Refuse to roll out anything that cannot start, see ErrZooKeeperRequired.
+	if err := r.validateClusteringMode(); err != nil {
+		return err
+	}
+	if _, err := security.GetCertLifetime(r.ClusterConfig.Tls, security.GetNodeCount(r.Spec.Nodes)); err != nil {
+		return err
+	}
+
 	// Register RBAC resources (ServiceAccount + Role + RoleBinding) for NiFi pods.
 	// Required for KubernetesLeaderElectionManager (leases) and
 	// KubernetesConfigMapStateProvider (configmaps) in Kubernetes-native clustering mode.
@@ -109,6 +123,18 @@ func (r *Reconciler) RegisterResources(ctx context.Context) error {
 	return nil
 }
 
+// validateClusteringMode checks that the product version supports the configured clustering backend.
+func (r *Reconciler) validateClusteringMode() error {
+	productVersion := r.GetImage().ProductVersion
+	if !strings.HasPrefix(productVersion, "1.") {
+		return nil
+	}
+	if r.ClusterConfig.ZookeeperConfigMapName == nil || *r.ClusterConfig.ZookeeperConfigMapName == "" {
+		return fmt.Errorf("product version %s: %w", productVersion, ErrZooKeeperRequired)
+	}
+	return nil
+}
+
 func (r *Reconciler) registerReportingTaskResources(ctx context.Context) error {
 	if r.ClusterConfig.CreateReportingTaskJob == nil || !r.ClusterConfig.CreateReportingTaskJob.Enable {
 		logger.Info("Reporting task job is disabled, skipping")
@@ -250,6 +276,7 @@ func (r *Reconciler) getAuthentication(ctx context.Context) (*security.Authentic
 		r.Client,
 		r.ClusterInfo.GetClusterName(),
 		r.ClusterConfig.Authentication,
+		r.ClusterConfig.Tls,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create authentication: %w", err)
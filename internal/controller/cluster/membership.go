@@ -0,0 +1,197 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+	"github.com/zncdatadev/nifi-operator/internal/common/nifiapi"
+	"github.com/zncdatadev/nifi-operator/internal/controller/node"
+)
+
+// MembershipRequeueInterval is how often the cluster membership is refreshed once all
+// resources are reconciled.
+const MembershipRequeueInterval = 1 * time.Minute
+
+// secretClassGVK is the SecretClass of the secret-operator, which issues the NiFi server certificates.
+var secretClassGVK = schema.GroupVersionKind{Group: "secrets.kubedoop.dev", Version: "v1alpha1", Kind: "SecretClass"}
+
+// caCertKey is the key of the CA certificate in the secret-operator autoTls CA secret.
+const caCertKey = "ca.crt"
+
+// ClusterMembership is the NiFi cluster membership reported by the REST API.
+type ClusterMembership struct {
+	Nodes              []nifiv1alpha1.NodeStatus
+	PrimaryNode        string
+	ClusterCoordinator string
+}
+
+// GetClusterMembership queries `/nifi-api/controller/cluster` on the first node that answers and
+// returns the connection state of every node of the NiFi cluster.
+//
+// It returns nil without error when no node is ready yet.
+func (r *Reconciler) GetClusterMembership(ctx context.Context) (*ClusterMembership, error) {
+	if r.IsStopped() {
+		return nil, nil
+	}
+
+	addresses, err := r.getNodeAddresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	options, err := r.getNifiAPIOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, port := "http", node.GetPort("http")
+	if r.ClusterConfig.Tls != nil {
+		scheme, port = "https", node.GetPort("https")
+	}
+
+	var errs []error
+	for _, address := range addresses {
+		client := nifiapi.NewClient(fmt.Sprintf("%s://%s:%d", scheme, address, port), options...)
+		cluster, err := client.GetCluster(ctx)
+		if err != nil {
+			logger.V(1).Info("Failed to query NiFi cluster membership", "address", address, "error", err.Error())
+			errs = append(errs, err)
+			continue
+		}
+		return newClusterMembership(cluster), nil
+	}
+
+	return nil, fmt.Errorf("failed to query NiFi cluster membership: %w", errors.Join(errs...))
+}
+
+func newClusterMembership(cluster *nifiapi.Cluster) *ClusterMembership {
+	membership := &ClusterMembership{
+		Nodes: make([]nifiv1alpha1.NodeStatus, 0, len(cluster.Nodes)),
+	}
+
+	for _, n := range cluster.Nodes {
+		membership.Nodes = append(membership.Nodes, nifiv1alpha1.NodeStatus{
+			NodeID:            n.NodeID,
+			Address:           n.Address,
+			APIPort:           n.APIPort,
+			Status:            n.Status,
+			Roles:             n.Roles,
+			ActiveThreadCount: n.ActiveThreadCount,
+		})
+		if n.HasRole(nifiapi.RolePrimaryNode) {
+			membership.PrimaryNode = n.Address
+		}
+		if n.HasRole(nifiapi.RoleClusterCoordinator) {
+			membership.ClusterCoordinator = n.Address
+		}
+	}
+
+	sort.Slice(membership.Nodes, func(i, j int) bool {
+		return membership.Nodes[i].Address < membership.Nodes[j].Address
+	})
+
+	return membership
+}
+
+// getNodeAddresses returns the pod DNS names of the ready node StatefulSets.
+func (r *Reconciler) getNodeAddresses(ctx context.Context) ([]string, error) {
+	namespace := r.Client.GetOwnerNamespace()
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.Client.Client.List(
+		ctx,
+		statefulSets,
+		ctrlclient.InNamespace(namespace),
+		ctrlclient.MatchingLabels(r.ClusterInfo.GetLabels()),
+	); err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets of cluster %s: %w", r.GetName(), err)
+	}
+
+	sort.Slice(statefulSets.Items, func(i, j int) bool {
+		return statefulSets.Items[i].Name < statefulSets.Items[j].Name
+	})
+
+	var addresses []string
+	for _, sts := range statefulSets.Items {
+		if sts.Status.ReadyReplicas == 0 {
+			continue
+		}
+		for i := int32(0); i < replicas(&sts); i++ {
+			addresses = append(addresses, fmt.Sprintf("%s-%d.%s.%s.svc.cluster.local", sts.Name, i, sts.Spec.ServiceName, namespace))
+		}
+	}
+	return addresses, nil
+}
+
+// getNifiAPIOptions returns the credentials and the CA certificate used to call the NiFi REST API.
+func (r *Reconciler) getNifiAPIOptions(ctx context.Context) ([]nifiapi.Option, error) {
+	var options []nifiapi.Option
+
+	if r.ClusterConfig.Tls == nil {
+		return options, nil
+	}
+
+	caCert, err := r.getServerCACert(ctx, r.ClusterConfig.Tls.ServerSecretClass)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, nifiapi.WithCACert(caCert))
+
+	auth, err := r.getAuthentication(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		username, password, err := auth.GetAdminCredentials(ctx, r.Client)
+		if err != nil {
+			return nil, err
+		}
+		if username == "" {
+			return nil, fmt.Errorf("cluster %s has no static AuthenticationClass, the operator has no admin credentials to query the NiFi REST API", r.GetName())
+		}
+		options = append(options, nifiapi.WithCredentials(username, password))
+	}
+
+	return options, nil
+}
+
+// getServerCACert returns the CA certificate of the secret-operator SecretClass issuing the
+// NiFi server certificates. Only the autoTls backend is supported.
+func (r *Reconciler) getServerCACert(ctx context.Context, secretClass string) ([]byte, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(secretClassGVK)
+	// SecretClass is not part of the scheme, use the controller-runtime client for the unstructured object.
+	if err := r.Client.Client.Get(ctx, ctrlclient.ObjectKey{Name: secretClass}, obj); err != nil {
+		return nil, fmt.Errorf("failed to get SecretClass %s: %w", secretClass, err)
+	}
+
+	name, _, _ := unstructured.NestedString(obj.Object, "spec", "backend", "autoTls", "ca", "secret", "name")
+	namespace, _, _ := unstructured.NestedString(obj.Object, "spec", "backend", "autoTls", "ca", "secret", "namespace")
+	if name == "" || namespace == "" {
+		return nil, fmt.Errorf("SecretClass %s does not use the autoTls backend, cannot trust the NiFi server certificate", secretClass)
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get CA secret %s/%s of SecretClass %s: %w", namespace, name, secretClass, err)
+	}
+
+	caCert, ok := secret.Data[caCertKey]
+	if !ok {
+		return nil, fmt.Errorf("CA secret %s/%s of SecretClass %s does not contain %s", namespace, name, secretClass, caCertKey)
+	}
+	return caCert, nil
+}
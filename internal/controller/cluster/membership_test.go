@@ -0,0 +1,40 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/zncdatadev/nifi-operator/internal/common/nifiapi"
+)
+
+func TestNewClusterMembership(t *testing.T) {
+	membership := newClusterMembership(&nifiapi.Cluster{
+		Nodes: []nifiapi.Node{
+			{
+				NodeID:  "b2",
+				Address: "simple-nifi-node-default-1.simple-nifi-node-default.default.svc.cluster.local",
+				Status:  "CONNECTED",
+				Roles:   []string{nifiapi.RolePrimaryNode},
+			},
+			{
+				NodeID:            "a1",
+				Address:           "simple-nifi-node-default-0.simple-nifi-node-default.default.svc.cluster.local",
+				Status:            "CONNECTED",
+				Roles:             []string{nifiapi.RoleClusterCoordinator},
+				ActiveThreadCount: 4,
+			},
+		},
+	})
+
+	if len(membership.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(membership.Nodes))
+	}
+	if membership.Nodes[0].NodeID != "a1" || membership.Nodes[0].ActiveThreadCount != 4 {
+		t.Errorf("expected nodes sorted by address, got %+v", membership.Nodes)
+	}
+	if membership.PrimaryNode != "simple-nifi-node-default-1.simple-nifi-node-default.default.svc.cluster.local" {
+		t.Errorf("unexpected primary node %q", membership.PrimaryNode)
+	}
+	if membership.ClusterCoordinator != "simple-nifi-node-default-0.simple-nifi-node-default.default.svc.cluster.local" {
+		t.Errorf("unexpected cluster coordinator %q", membership.ClusterCoordinator)
+	}
+}
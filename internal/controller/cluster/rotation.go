@@ -0,0 +1,136 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zncdatadev/operator-go/pkg/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/zncdatadev/nifi-operator/internal/common/security"
+)
+
+// certRotation is the rotation planned for the certificates of the node pods.
+type certRotation struct {
+	// Next is when the next node is restarted, nil when no pod mounts an expiring certificate.
+	Next *time.Time
+	// Restart is the pod to restart now, nil when no rotation is due or a node is not ready.
+	Restart *corev1.Pod
+	// Unannotated are the pods the secret-operator recorded no certificate expiry on.
+	Unannotated []string
+}
+
+// RotateCertificates restarts the node whose certificate expires first once it is due, one node at a time,
+// and returns when the next node is restarted.
+//
+// The secret-operator records the expiry of the certificates it issues on the pod. A restarted pod is
+// recreated by its StatefulSet and mounts a newly issued certificate. The next node is only restarted
+// once every node is ready again.
+func (r *Reconciler) RotateCertificates(ctx context.Context) (*metav1.Time, error) {
+	if r.ClusterConfig == nil || r.ClusterConfig.Tls == nil || r.IsStopped() {
+		return nil, nil
+	}
+
+	namespace := r.Client.GetOwnerNamespace()
+	selector := r.ClusterInfo.GetLabels()
+	selector[constants.LabelKubernetesComponent] = "node"
+
+	pods := &corev1.PodList{}
+	if err := r.Client.Client.List(ctx, pods, ctrlclient.InNamespace(namespace), ctrlclient.MatchingLabels(selector)); err != nil {
+		return nil, fmt.Errorf("failed to list pods of cluster %s: %w", r.GetName(), err)
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.Client.Client.List(ctx, statefulSets, ctrlclient.InNamespace(namespace), ctrlclient.MatchingLabels(selector)); err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets of cluster %s: %w", r.GetName(), err)
+	}
+
+	// The restart window is derived from the same node count as the certificate lifetime.
+	nodes := security.GetNodeCount(r.Spec.Nodes)
+	lifetime, err := security.GetCertLifetime(r.ClusterConfig.Tls, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	rotation := planCertRotation(pods.Items, statefulSets.Items, security.GetCertRestartWindow(lifetime, nodes), time.Now())
+	if rotation.Restart != nil {
+		logger.Info("Restarting node to renew its certificate", "cluster", r.GetName(), "pod", rotation.Restart.Name)
+		if err := r.Client.Client.Delete(ctx, rotation.Restart); ctrlclient.IgnoreNotFound(err) != nil {
+			return nil, fmt.Errorf("failed to restart pod %s: %w", rotation.Restart.Name, err)
+		}
+	}
+
+	if rotation.Next == nil {
+		// Without an expiry on the pods no rotation is planned, unlike a cluster without TLS the
+		// certificates still expire.
+		if len(rotation.Unannotated) > 0 {
+			logger.Info("No certificate expiry recorded on the node pods, no rotation is planned",
+				"cluster", r.GetName(), "pods", rotation.Unannotated, "annotation", constants.PrefixLabelRestarterExpiresAt)
+		}
+		return nil, nil
+	}
+	next := metav1.NewTime(*rotation.Next)
+	return &next, nil
+}
+
+// planCertRotation returns when the next node is restarted and the pod to restart now. A pod is restarted
+// restartWindow before its first certificate expires, when every StatefulSet is ready and no pod
+// is terminating.
+func planCertRotation(pods []corev1.Pod, statefulSets []appsv1.StatefulSet, restartWindow time.Duration, now time.Time) certRotation {
+	var rotation certRotation
+
+	settled := len(notReadyStatefulSets(statefulSets)) == 0 && len(updatingStatefulSets(statefulSets)) == 0
+
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			settled = false
+			continue
+		}
+		expiry, ok := getCertExpiry(pod)
+		if !ok {
+			rotation.Unannotated = append(rotation.Unannotated, pod.Name)
+			continue
+		}
+		restartAt := expiry.Add(-restartWindow)
+		if rotation.Next == nil || restartAt.Before(*rotation.Next) {
+			rotation.Next = &restartAt
+			rotation.Restart = pod
+		}
+	}
+
+	if !settled || rotation.Next == nil || rotation.Next.After(now) {
+		rotation.Restart = nil
+	}
+	return rotation
+}
+
+// getCertExpiry returns when the first certificate mounted in the pod expires. The secret-operator annotates
+// the pod with `restarter.kubedoop.dev/expires-at.<RFC3339>: <volume>` for every certificate it issues,
+// the expiry is also accepted as the value of the annotation.
+func getCertExpiry(pod *corev1.Pod) (time.Time, bool) {
+	var first time.Time
+	for key, value := range pod.Annotations {
+		suffix, ok := strings.CutPrefix(key, constants.PrefixLabelRestarterExpiresAt)
+		if !ok {
+			continue
+		}
+		expiry, err := time.Parse(time.RFC3339, suffix)
+		if err != nil {
+			if expiry, err = time.Parse(time.RFC3339, value); err != nil {
+				continue
+			}
+		}
+		if first.IsZero() || expiry.Before(first) {
+			first = expiry
+		}
+	}
+	return first, !first.IsZero()
+}
@@ -0,0 +1,114 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zncdatadev/operator-go/pkg/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zncdatadev/nifi-operator/internal/common/security"
+)
+
+func newExpiringPod(name string, expiries ...time.Time) corev1.Pod {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{}}}
+	for i, expiry := range expiries {
+		pod.Annotations[constants.PrefixLabelRestarterExpiresAt+expiry.Format(time.RFC3339)] = "volume-" + string(rune('a'+i))
+	}
+	return pod
+}
+
+func TestGetCertExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := getCertExpiry(&corev1.Pod{}); ok {
+		t.Error("expected no expiry without annotations")
+	}
+
+	pod := newExpiringPod("simple-nifi-node-default-0", now.Add(2*time.Hour), now.Add(time.Hour))
+	if expiry, ok := getCertExpiry(&pod); !ok || !expiry.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected the first expiry %s, got %s", now.Add(time.Hour), expiry)
+	}
+
+	pod = corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		constants.PrefixLabelRestarterExpiresAt + "server-tls": now.Format(time.RFC3339),
+	}}}
+	if expiry, ok := getCertExpiry(&pod); !ok || !expiry.Equal(now) {
+		t.Errorf("expected the expiry of the annotation value %s, got %s", now, expiry)
+	}
+}
+
+func TestPlanCertRotation(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	ready := []appsv1.StatefulSet{newStatefulSet("simple-nifi-node-default", 2, 2, 2)}
+	window := security.GetCertRestartWindow(0, 2)
+
+	t.Run("not due", func(t *testing.T) {
+		pods := []corev1.Pod{
+			newExpiringPod("simple-nifi-node-default-0", now.Add(2*time.Hour)),
+			newExpiringPod("simple-nifi-node-default-1", now.Add(time.Hour)),
+		}
+		rotation := planCertRotation(pods, ready, window, now)
+		if rotation.Next == nil || !rotation.Next.Equal(now.Add(time.Hour-window)) {
+			t.Errorf("expected the next rotation before the first expiry, got %v", rotation.Next)
+		}
+		if rotation.Restart != nil {
+			t.Errorf("expected no restart before the rotation is due, got %s", rotation.Restart.Name)
+		}
+	})
+
+	t.Run("due", func(t *testing.T) {
+		pods := []corev1.Pod{
+			newExpiringPod("simple-nifi-node-default-0", now.Add(window/2)),
+			newExpiringPod("simple-nifi-node-default-1", now.Add(window/3)),
+		}
+		rotation := planCertRotation(pods, ready, window, now)
+		if rotation.Restart == nil || rotation.Restart.Name != "simple-nifi-node-default-1" {
+			t.Errorf("expected to restart the pod expiring first, got %+v", rotation.Restart)
+		}
+	})
+
+	t.Run("one node at a time", func(t *testing.T) {
+		pods := []corev1.Pod{
+			newExpiringPod("simple-nifi-node-default-0", now),
+			newExpiringPod("simple-nifi-node-default-1", now.Add(time.Hour)),
+		}
+		notReady := []appsv1.StatefulSet{newStatefulSet("simple-nifi-node-default", 2, 1, 2)}
+		if rotation := planCertRotation(pods, notReady, window, now); rotation.Restart != nil || rotation.Next == nil {
+			t.Errorf("expected no restart while a node is not ready, got %+v", rotation)
+		}
+
+		terminating := metav1.NewTime(now)
+		pods[1].DeletionTimestamp = &terminating
+		if rotation := planCertRotation(pods, ready, window, now); rotation.Restart != nil {
+			t.Errorf("expected no restart while a pod is terminating, got %s", rotation.Restart.Name)
+		}
+	})
+
+	t.Run("no certificates", func(t *testing.T) {
+		pods := []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi-node-default-0"}}}
+		rotation := planCertRotation(pods, ready, window, now)
+		if rotation.Next != nil || rotation.Restart != nil {
+			t.Errorf("expected no rotation without expiring certificates, got %+v", rotation)
+		}
+		if len(rotation.Unannotated) != 1 || rotation.Unannotated[0] != "simple-nifi-node-default-0" {
+			t.Errorf("expected the pod without an expiry reported, got %v", rotation.Unannotated)
+		}
+	})
+
+	t.Run("some pods without certificates", func(t *testing.T) {
+		pods := []corev1.Pod{
+			newExpiringPod("simple-nifi-node-default-0", now.Add(time.Hour)),
+			{ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi-node-default-1"}},
+		}
+		rotation := planCertRotation(pods, ready, window, now)
+		if rotation.Next == nil || !rotation.Next.Equal(now.Add(time.Hour-window)) {
+			t.Errorf("expected the rotation of the annotated pod, got %v", rotation.Next)
+		}
+		if len(rotation.Unannotated) != 1 || rotation.Unannotated[0] != "simple-nifi-node-default-1" {
+			t.Errorf("expected the pod without an expiry reported, got %v", rotation.Unannotated)
+		}
+	})
+}
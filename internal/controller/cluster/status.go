@@ -0,0 +1,288 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zncdatadev/operator-go/pkg/status"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Condition types reported on the NifiCluster status in addition to
+// status.ConditionTypeAvailable and status.ConditionTypeProgressing.
+const (
+	ConditionTypeDegraded             = "Degraded"
+	ConditionTypeReconciliationPaused = "ReconciliationPaused"
+)
+
+// Condition reasons reported on the NifiCluster status.
+const (
+	ConditionReasonNodesReady             = "NodesReady"
+	ConditionReasonNodesNotReady          = "NodesNotReady"
+	ConditionReasonNoNodes                = "NoNodes"
+	ConditionReasonStopped                = "Stopped"
+	ConditionReasonRollingUpdate          = "RollingUpdate"
+	ConditionReasonReportingTaskPending   = "ReportingTaskPending"
+	ConditionReasonReconciled             = "Reconciled"
+	ConditionReasonAsExpected             = "AsExpected"
+	ConditionReasonDependencyLookupFailed = "DependencyLookupFailed"
+	ConditionReasonZooKeeperRequired      = "ZooKeeperRequired"
+	ConditionReasonReconcileFailed        = "ReconcileFailed"
+	ConditionReasonSensitiveKeyMissing    = "SensitiveKeySecretMissing"
+	ConditionReasonReportingTaskFailed    = "ReportingTaskJobFailed"
+	ConditionReasonPaused                 = "Paused"
+	ConditionReasonNotPaused              = "NotPaused"
+)
+
+// clusterState is the observed state of the resources owned by a NifiCluster,
+// the conditions are derived from it.
+type clusterState struct {
+	Paused  bool
+	Stopped bool
+
+	StatefulSets []appsv1.StatefulSet
+	Jobs         []batchv1.Job
+
+	// SensitiveKeySecretFound reports whether the sensitive properties key secret exists.
+	SensitiveKeySecretFound bool
+	SensitiveKeySecretName  string
+
+	// DependencyErr is the error returned while looking up the resources the cluster depends on,
+	// e.g. AuthenticationClasses, before any resource is reconciled.
+	DependencyErr error
+	// ReconcileErr is the error returned while reconciling the owned resources.
+	ReconcileErr error
+}
+
+// GetConditions observes the resources owned by the cluster and returns the
+// Available, Progressing, Degraded and ReconciliationPaused conditions.
+//
+// registerErr is the error returned by RegisterResources, runErr the one returned by Run.
+func (r *Reconciler) GetConditions(ctx context.Context, registerErr, runErr error) ([]metav1.Condition, error) {
+	state, err := r.observe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state.DependencyErr = registerErr
+	state.ReconcileErr = runErr
+
+	return computeConditions(state), nil
+}
+
+func (r *Reconciler) observe(ctx context.Context) (*clusterState, error) {
+	state := &clusterState{
+		Paused:  r.IsPaused(ctx),
+		Stopped: r.IsStopped(),
+	}
+
+	namespace := r.Client.GetOwnerNamespace()
+	selector := ctrlclient.MatchingLabels(r.ClusterInfo.GetLabels())
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.Client.Client.List(ctx, statefulSets, ctrlclient.InNamespace(namespace), selector); err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets of cluster %s: %w", r.GetName(), err)
+	}
+	state.StatefulSets = statefulSets.Items
+
+	jobs := &batchv1.JobList{}
+	if err := r.Client.Client.List(ctx, jobs, ctrlclient.InNamespace(namespace), selector); err != nil {
+		return nil, fmt.Errorf("failed to list jobs of cluster %s: %w", r.GetName(), err)
+	}
+	state.Jobs = jobs.Items
+
+	state.SensitiveKeySecretFound = true
+	if r.ClusterConfig != nil && r.ClusterConfig.SensitiveProperties != nil {
+		state.SensitiveKeySecretName = r.ClusterConfig.SensitiveProperties.KeySecret
+		secret := &corev1.Secret{}
+		key := ctrlclient.ObjectKey{Namespace: namespace, Name: state.SensitiveKeySecretName}
+		if err := r.Client.Get(ctx, key, secret); err != nil {
+			if ctrlclient.IgnoreNotFound(err) != nil {
+				return nil, fmt.Errorf("failed to get sensitive key secret %s: %w", state.SensitiveKeySecretName, err)
+			}
+			state.SensitiveKeySecretFound = false
+		}
+	}
+
+	return state, nil
+}
+
+func computeConditions(state *clusterState) []metav1.Condition {
+	return []metav1.Condition{
+		availableCondition(state),
+		progressingCondition(state),
+		degradedCondition(state),
+		pausedCondition(state),
+	}
+}
+
+// notReadyStatefulSets returns the names of the StatefulSets which do not have all replicas ready.
+func notReadyStatefulSets(statefulSets []appsv1.StatefulSet) []string {
+	var names []string
+	for _, sts := range statefulSets {
+		if sts.Status.ReadyReplicas < replicas(&sts) {
+			names = append(names, sts.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// updatingStatefulSets returns the names of the StatefulSets with a rollout in progress.
+func updatingStatefulSets(statefulSets []appsv1.StatefulSet) []string {
+	var names []string
+	for _, sts := range statefulSets {
+		if sts.Status.ObservedGeneration < sts.Generation ||
+			sts.Status.UpdatedReplicas < replicas(&sts) ||
+			(sts.Status.UpdateRevision != "" && sts.Status.CurrentRevision != sts.Status.UpdateRevision) {
+			names = append(names, sts.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func replicas(sts *appsv1.StatefulSet) int32 {
+	if sts.Spec.Replicas == nil {
+		return 1
+	}
+	return *sts.Spec.Replicas
+}
+
+func jobFinished(job *batchv1.Job) (bool, batchv1.JobConditionType) {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true, c.Type
+		}
+	}
+	return false, ""
+}
+
+func availableCondition(state *clusterState) metav1.Condition {
+	condition := metav1.Condition{Type: status.ConditionTypeAvailable}
+
+	switch notReady := notReadyStatefulSets(state.StatefulSets); {
+	case state.Stopped:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ConditionReasonStopped
+		condition.Message = "The cluster is stopped"
+	case len(state.StatefulSets) == 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ConditionReasonNoNodes
+		condition.Message = "No node StatefulSet exists yet"
+	case len(notReady) > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ConditionReasonNodesNotReady
+		condition.Message = fmt.Sprintf("StatefulSets not ready: %s", strings.Join(notReady, ", "))
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ConditionReasonNodesReady
+		condition.Message = "All nodes are ready"
+	}
+
+	return condition
+}
+
+func progressingCondition(state *clusterState) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    status.ConditionTypeProgressing,
+		Status:  metav1.ConditionFalse,
+		Reason:  ConditionReasonReconciled,
+		Message: "All resources are reconciled",
+	}
+
+	if state.DependencyErr != nil {
+		condition.Reason = ConditionReasonDependencyLookupFailed
+		condition.Message = "The rollout is blocked, see the Degraded condition"
+		return condition
+	}
+
+	if updating := updatingStatefulSets(state.StatefulSets); len(updating) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ConditionReasonRollingUpdate
+		condition.Message = fmt.Sprintf("StatefulSets rolling out: %s", strings.Join(updating, ", "))
+		return condition
+	}
+
+	if !state.Stopped {
+		if notReady := notReadyStatefulSets(state.StatefulSets); len(notReady) > 0 {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = ConditionReasonNodesNotReady
+			condition.Message = fmt.Sprintf("Waiting for StatefulSets to become ready: %s", strings.Join(notReady, ", "))
+			return condition
+		}
+	}
+
+	for i := range state.Jobs {
+		if finished, _ := jobFinished(&state.Jobs[i]); !finished {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = ConditionReasonReportingTaskPending
+			condition.Message = fmt.Sprintf("Waiting for job %s to complete", state.Jobs[i].Name)
+			return condition
+		}
+	}
+
+	return condition
+}
+
+func degradedCondition(state *clusterState) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    ConditionTypeDegraded,
+		Status:  metav1.ConditionTrue,
+		Reason:  ConditionReasonAsExpected,
+		Message: "The cluster is working as expected",
+	}
+
+	switch {
+	case errors.Is(state.DependencyErr, ErrZooKeeperRequired):
+		condition.Reason = ConditionReasonZooKeeperRequired
+		condition.Message = state.DependencyErr.Error()
+		return condition
+	case state.DependencyErr != nil:
+		condition.Reason = ConditionReasonDependencyLookupFailed
+		condition.Message = state.DependencyErr.Error()
+		return condition
+	case state.ReconcileErr != nil:
+		condition.Reason = ConditionReasonReconcileFailed
+		condition.Message = state.ReconcileErr.Error()
+		return condition
+	case !state.SensitiveKeySecretFound:
+		condition.Reason = ConditionReasonSensitiveKeyMissing
+		condition.Message = fmt.Sprintf("Sensitive key secret %s not found", state.SensitiveKeySecretName)
+		return condition
+	}
+
+	for i := range state.Jobs {
+		if _, result := jobFinished(&state.Jobs[i]); result == batchv1.JobFailed {
+			condition.Reason = ConditionReasonReportingTaskFailed
+			condition.Message = fmt.Sprintf("Job %s failed", state.Jobs[i].Name)
+			return condition
+		}
+	}
+
+	condition.Status = metav1.ConditionFalse
+	return condition
+}
+
+func pausedCondition(state *clusterState) metav1.Condition {
+	if state.Paused {
+		return metav1.Condition{
+			Type:    ConditionTypeReconciliationPaused,
+			Status:  metav1.ConditionTrue,
+			Reason:  ConditionReasonPaused,
+			Message: "Reconciliation is paused by clusterOperation.reconciliationPaused",
+		}
+	}
+	return metav1.Condition{
+		Type:    ConditionTypeReconciliationPaused,
+		Status:  metav1.ConditionFalse,
+		Reason:  ConditionReasonNotPaused,
+		Message: "Reconciliation is active",
+	}
+}
@@ -0,0 +1,150 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zncdatadev/operator-go/pkg/status"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func newStatefulSet(name string, replicas, ready, updated int32) appsv1.StatefulSet {
+	return appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+		Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To(replicas)},
+		Status: appsv1.StatefulSetStatus{
+			ObservedGeneration: 1,
+			ReadyReplicas:      ready,
+			UpdatedReplicas:    updated,
+		},
+	}
+}
+
+func assertCondition(t *testing.T, conditions []metav1.Condition, conditionType string, expected metav1.ConditionStatus, reason string) {
+	t.Helper()
+	condition := apimeta.FindStatusCondition(conditions, conditionType)
+	if condition == nil {
+		t.Fatalf("condition %s not found", conditionType)
+	}
+	if condition.Status != expected || condition.Reason != reason {
+		t.Errorf("%s: expected %s/%s, got %s/%s", conditionType, expected, reason, condition.Status, condition.Reason)
+	}
+}
+
+func TestComputeConditions_Ready(t *testing.T) {
+	conditions := computeConditions(&clusterState{
+		StatefulSets:            []appsv1.StatefulSet{newStatefulSet("simple-nifi-node-default", 2, 2, 2)},
+		SensitiveKeySecretFound: true,
+	})
+
+	assertCondition(t, conditions, status.ConditionTypeAvailable, metav1.ConditionTrue, ConditionReasonNodesReady)
+	assertCondition(t, conditions, status.ConditionTypeProgressing, metav1.ConditionFalse, ConditionReasonReconciled)
+	assertCondition(t, conditions, ConditionTypeDegraded, metav1.ConditionFalse, ConditionReasonAsExpected)
+	assertCondition(t, conditions, ConditionTypeReconciliationPaused, metav1.ConditionFalse, ConditionReasonNotPaused)
+}
+
+func TestComputeConditions_RollingUpdate(t *testing.T) {
+	conditions := computeConditions(&clusterState{
+		StatefulSets:            []appsv1.StatefulSet{newStatefulSet("simple-nifi-node-default", 3, 2, 1)},
+		SensitiveKeySecretFound: true,
+	})
+
+	assertCondition(t, conditions, status.ConditionTypeAvailable, metav1.ConditionFalse, ConditionReasonNodesNotReady)
+	assertCondition(t, conditions, status.ConditionTypeProgressing, metav1.ConditionTrue, ConditionReasonRollingUpdate)
+}
+
+func TestComputeConditions_Degraded(t *testing.T) {
+	ready := []appsv1.StatefulSet{newStatefulSet("simple-nifi-node-default", 1, 1, 1)}
+	failedJob := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi-create-reporting-task-1-27-0"},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		state  *clusterState
+		reason string
+	}{
+		{
+			name:   "dependency lookup",
+			state:  &clusterState{StatefulSets: ready, SensitiveKeySecretFound: true, DependencyErr: errors.New("authentication class not found")},
+			reason: ConditionReasonDependencyLookupFailed,
+		},
+		{
+			name:   "zookeeper required",
+			state:  &clusterState{SensitiveKeySecretFound: true, DependencyErr: fmt.Errorf("product version 1.27.0: %w", ErrZooKeeperRequired)},
+			reason: ConditionReasonZooKeeperRequired,
+		},
+		{
+			name:   "reconcile",
+			state:  &clusterState{StatefulSets: ready, SensitiveKeySecretFound: true, ReconcileErr: errors.New("conflict")},
+			reason: ConditionReasonReconcileFailed,
+		},
+		{
+			name:   "sensitive key secret",
+			state:  &clusterState{StatefulSets: ready, SensitiveKeySecretName: "nifi-sensitive"},
+			reason: ConditionReasonSensitiveKeyMissing,
+		},
+		{
+			name:   "reporting task job",
+			state:  &clusterState{StatefulSets: ready, SensitiveKeySecretFound: true, Jobs: []batchv1.Job{failedJob}},
+			reason: ConditionReasonReportingTaskFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assertCondition(t, computeConditions(tc.state), ConditionTypeDegraded, metav1.ConditionTrue, tc.reason)
+		})
+	}
+}
+
+func TestComputeConditions_PausedAndStopped(t *testing.T) {
+	conditions := computeConditions(&clusterState{
+		Paused:                  true,
+		Stopped:                 true,
+		StatefulSets:            []appsv1.StatefulSet{newStatefulSet("simple-nifi-node-default", 0, 0, 0)},
+		SensitiveKeySecretFound: true,
+	})
+
+	assertCondition(t, conditions, status.ConditionTypeAvailable, metav1.ConditionFalse, ConditionReasonStopped)
+	assertCondition(t, conditions, ConditionTypeReconciliationPaused, metav1.ConditionTrue, ConditionReasonPaused)
+}
+
+func TestValidateClusteringMode(t *testing.T) {
+	cases := []struct {
+		name      string
+		version   string
+		zookeeper *string
+		wantErr   bool
+	}{
+		{name: "2.x without zookeeper", version: "2.4.0"},
+		{name: "1.x with zookeeper", version: "1.27.0", zookeeper: ptr.To("simple-nifi-znode")},
+		{name: "1.x without zookeeper", version: "1.27.0", wantErr: true},
+		{name: "1.x with empty zookeeper", version: "1.27.0", zookeeper: ptr.To(""), wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Reconciler{ClusterConfig: &nifiv1alpha1.ClusterConfigSpec{ZookeeperConfigMapName: tc.zookeeper}}
+			r.Spec = &nifiv1alpha1.NifiClusterSpec{
+				Image: &nifiv1alpha1.ImageSpec{ProductVersion: tc.version, PullPolicy: ptr.To(corev1.PullIfNotPresent)},
+			}
+
+			err := r.validateClusteringMode()
+			if tc.wantErr != errors.Is(err, ErrZooKeeperRequired) {
+				t.Errorf("expected ErrZooKeeperRequired: %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -18,13 +18,21 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	operatorclient "github.com/zncdatadev/operator-go/pkg/client"
 	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
 	"github.com/zncdatadev/nifi-operator/internal/controller/cluster"
@@ -45,6 +53,8 @@ type NifiClusterReconciler struct {
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;delete
 // +kubebuilder:rbac:groups=authentication.kubedoop.dev,resources=authenticationclasses,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
@@ -52,6 +62,7 @@ type NifiClusterReconciler struct {
 // +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=secrets.kubedoop.dev,resources=secretclasses,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -86,18 +97,116 @@ func (r *NifiClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	reconciler := cluster.NewReconciler(resourceClient, clientinfo, &instance.Spec)
 
-	if err := reconciler.RegisterResources(ctx); err != nil {
-		logger.Error(err, "Failed to register resources for NifiCluster", "name", instance.Name)
-		return ctrl.Result{}, err
+	var (
+		result ctrl.Result
+		runErr error
+	)
+	registerErr := reconciler.RegisterResources(ctx)
+	if registerErr != nil {
+		logger.Error(registerErr, "Failed to register resources for NifiCluster", "name", instance.Name)
+	} else {
+		result, runErr = reconciler.Run(ctx)
+	}
+
+	// Restart the nodes one at a time before their certificates expire.
+	var nextCertRotation *metav1.Time
+	if registerErr == nil && runErr == nil && !reconciler.IsPaused(ctx) {
+		nextCertRotation, runErr = reconciler.RotateCertificates(ctx)
+	}
+
+	if err := r.updateStatus(ctx, instance, reconciler, registerErr, runErr, nextCertRotation); err != nil {
+		logger.Error(err, "Failed to update NifiCluster status", "name", instance.Name)
+		if registerErr == nil && runErr == nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if errors.Is(registerErr, cluster.ErrZooKeeperRequired) {
+		// Retrying does not help until the spec changes, which triggers a new reconcile.
+		return ctrl.Result{}, reconcile.TerminalError(registerErr)
+	}
+	if registerErr != nil {
+		return ctrl.Result{}, registerErr
+	}
+	if runErr == nil && result.IsZero() {
+		// Refresh the NiFi cluster membership periodically, it is not driven by watch events.
+		result.RequeueAfter = cluster.MembershipRequeueInterval
+	}
+	if nextCertRotation != nil && runErr == nil {
+		if untilRotation := time.Until(nextCertRotation.Time); untilRotation > 0 && untilRotation < result.RequeueAfter {
+			result.RequeueAfter = untilRotation
+		}
+	}
+	return result, runErr
+}
+
+// updateStatus writes the conditions observed by the cluster reconciler, the observed generation
+// and the next certificate rotation to the NifiCluster status.
+func (r *NifiClusterReconciler) updateStatus(
+	ctx context.Context,
+	instance *nifiv1alpha1.NifiCluster,
+	reconciler *cluster.Reconciler,
+	registerErr error,
+	runErr error,
+	nextCertRotation *metav1.Time,
+) error {
+	conditions, err := reconciler.GetConditions(ctx, registerErr, runErr)
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(instance.DeepCopy())
+	for _, condition := range conditions {
+		condition.ObservedGeneration = instance.Generation
+		apimeta.SetStatusCondition(&instance.Status.Conditions, condition)
+	}
+	instance.Status.ObservedGeneration = instance.Generation
+
+	if !reconciler.IsPaused(ctx) {
+		r.updateMembership(ctx, instance, reconciler)
+		// The planned rotation is kept when it could not be observed.
+		if registerErr == nil && runErr == nil {
+			instance.Status.NextCertificateRotation = nextCertRotation
+		}
+	}
+
+	return r.Status().Patch(ctx, instance, patch)
+}
+
+// updateMembership records the NiFi cluster membership in the status. When the REST API cannot be
+// reached the last known membership is kept, it is only cleared when no node is running.
+func (r *NifiClusterReconciler) updateMembership(
+	ctx context.Context,
+	instance *nifiv1alpha1.NifiCluster,
+	reconciler *cluster.Reconciler,
+) {
+	membership, err := reconciler.GetClusterMembership(ctx)
+	if err != nil {
+		logger.Error(err, "Failed to get NiFi cluster membership", "name", instance.Name)
+		return
+	}
+
+	if membership == nil {
+		instance.Status.Nodes = nil
+		instance.Status.PrimaryNode = ""
+		instance.Status.ClusterCoordinator = ""
+		return
 	}
 
-	return reconciler.Run(ctx)
+	instance.Status.Nodes = membership.Nodes
+	instance.Status.PrimaryNode = membership.PrimaryNode
+	instance.Status.ClusterCoordinator = membership.ClusterCoordinator
 }
 
 // SetupWithManager sets up the controller with the Manager.
+//
+// Status updates do not change the generation of the NifiCluster and do not trigger a reconcile,
+// the membership is refreshed by the periodic requeue.
 func (r *NifiClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&nifiv1alpha1.NifiCluster{}).
+		For(&nifiv1alpha1.NifiCluster{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&batchv1.Job{}).
 		Named("nificluster").
 		Complete(r)
 }
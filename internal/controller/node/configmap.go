@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/builder"
 	"github.com/zncdatadev/operator-go/pkg/client"
 	"github.com/zncdatadev/operator-go/pkg/config/properties"
@@ -21,16 +22,11 @@ import (
 	"github.com/zncdatadev/nifi-operator/internal/common/security"
 )
 
-const (
-	DefaultServerTlsStorePassword = "changeit"
-	DefaultServerTlsKeyPassword   = "changeit"
-)
-
 var (
 	NifiRoot                 = path.Join(constants.KubedoopRoot, "nifi")
 	NifiConfigDir            = path.Join(NifiRoot, "conf")
 	NifiSensitivePropertyDir = path.Join(NifiRoot, "sensitiveproperty")
-	NifiServerTlsDir         = path.Join(NifiRoot, "server-tls")
+	NifiServerTlsDir         = security.ServerTlsDir
 )
 
 func nifiRepository(name string) string {
@@ -58,6 +54,11 @@ type NifiConfigMapBuilder struct {
 	RoleGroupName  string
 	Config         *nifiv1alpha1.ConfigSpec
 	Authentication *security.Authentication
+
+	// JVMArgumentOverrides are applied in order, role first and role group last.
+	JVMArgumentOverrides []*nifiv1alpha1.JVMArgumentOverridesSpec
+	// Overrides are the role overrides merged with the role group overrides.
+	Overrides *commonsv1alpha1.OverridesSpec
 }
 
 func NewNifiConfigBuilder(
@@ -67,6 +68,8 @@ func NewNifiConfigBuilder(
 	roleGroupInfo reconciler.RoleGroupInfo,
 	config *nifiv1alpha1.ConfigSpec,
 	authentication *security.Authentication,
+	jvmArgumentOverrides []*nifiv1alpha1.JVMArgumentOverridesSpec,
+	overrides *commonsv1alpha1.OverridesSpec,
 ) *NifiConfigMapBuilder {
 	return &NifiConfigMapBuilder{
 		ConfigMapBuilder: *builder.NewConfigMapBuilder(
@@ -77,38 +80,118 @@ func NewNifiConfigBuilder(
 				o.Annotations = roleGroupInfo.GetAnnotations()
 			},
 		),
-		ClusterConfig:  clusterConfig,
-		Image:          image,
-		ClusterName:    roleGroupInfo.ClusterName,
-		RoleName:       roleGroupInfo.RoleName,
-		RoleGroupName:  roleGroupInfo.RoleGroupName,
-		Config:         config,
-		Authentication: authentication,
+		ClusterConfig:        clusterConfig,
+		Image:                image,
+		ClusterName:          roleGroupInfo.ClusterName,
+		RoleName:             roleGroupInfo.RoleName,
+		RoleGroupName:        roleGroupInfo.RoleGroupName,
+		Config:               config,
+		Authentication:       authentication,
+		JVMArgumentOverrides: jvmArgumentOverrides,
+		Overrides:            overrides,
 	}
 }
 
 func (b *NifiConfigMapBuilder) Build(ctx context.Context) (ctrlclient.Object, error) {
+	// NiFi only authorizes authenticated users, authorizers.xml is not generated without authentication.
+	if b.ClusterConfig.Authentication == nil && b.getOpa() != nil {
+		return nil, fmt.Errorf("clusterConfig.authorization.opa requires clusterConfig.authentication")
+	}
 
-	bootstarpProperties := b.getBootstrapConfig()
+	bootstarpProperties, err := b.getBootstrapConfig()
+	if err != nil {
+		return nil, err
+	}
 
-	b.AddItem("bootstrap.conf", bootstarpProperties)
+	b.AddItem(BootstrapConfFile, applyKeyValueOverrides(bootstarpProperties, b.getConfigOverrides(BootstrapConfFile)))
+	b.AddItem(securityPropertiesFile, getSecurityProperties())
 
 	nifiProperties, err := b.getNifiProperties(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	b.AddItem("nifi.properties", nifiProperties)
+	b.AddItem(NifiPropertiesFile, nifiProperties)
+
+	logback, err := getLogbackConfig(b.Config, b.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	b.AddItem(LogbackFile, logback)
+
+	if IsVectorEnabled(b.Config) {
+		namespace := b.Client.GetOwnerNamespace()
+		vectorConfig, err := getVectorConfig(ctx, b.Client.Client, namespace, b.ClusterName, b.RoleName, b.RoleGroupName, b.ClusterConfig.VectorAggregatorConfigMapName)
+		if err != nil {
+			return nil, err
+		}
+		b.AddItem(builder.VectorConfigFileName, vectorConfig)
+	}
 
+	xmlFiles := map[string]string{
+		StateManagementFile: b.getStateManagementConfig(),
+	}
 	if b.ClusterConfig.Authentication != nil {
-		b.AddItem("login-identity-providers.xml", b.Authentication.GetLoginIdentiryProvider())
+		// Without login identity provider, e.g. with client certificates only, the file is not generated.
+		if b.Authentication.HasLoginIdentityProvider() {
+			xmlFiles[LoginIdentityProvidersFile] = b.Authentication.GetLoginIdentiryProvider()
+		}
+		authorizers, err := b.getAuthorizersConfig()
+		if err != nil {
+			return nil, err
+		}
+		xmlFiles[AuthorizersFile] = authorizers
+
+		if b.getOpa() == nil && b.Authentication.HasGroupPolicies() {
+			b.AddItem(InitialUsersFile, b.Authentication.GetInitialTenants(b.getNodeIdentities()))
+			b.AddItem(InitialAuthorizationsFile, b.Authentication.GetInitialAuthorizations(b.getNodeIdentities()))
+		}
 	}
 
-	b.AddItem("state-management.xml", b.getStateManagementConfig())
+	for _, name := range slices.Sorted(maps.Keys(xmlFiles)) {
+		data, err := applyXMLPropertyOverrides(xmlFiles[name], b.getConfigOverrides(name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply config overrides to %s: %w", name, err)
+		}
+		b.AddItem(name, data)
+	}
 
 	return b.GetObject(), nil
 }
 
+// getAuthorizersConfig returns authorizers.xml, the decisions are delegated to OPA when
+// `clusterConfig.authorization.opa` is set, otherwise the users and policies are managed in NiFi.
+func (b *NifiConfigMapBuilder) getAuthorizersConfig() (string, error) {
+	if opa := b.getOpa(); opa != nil {
+		return security.GetOpaAuthorizers(opa)
+	}
+	return b.Authentication.GetAuthorizers(NifiRepositoryMouhtPath["database"], b.getNodeIdentities()), nil
+}
+
+func (b *NifiConfigMapBuilder) getOpa() *nifiv1alpha1.OpaSpec {
+	if b.ClusterConfig.Authorization == nil {
+		return nil
+	}
+	return b.ClusterConfig.Authorization.Opa
+}
+
+// getNodeIdentities returns the identities of the cluster nodes. The nodes only authenticate to each other
+// with their certificates, so there are none without TLS.
+func (b *NifiConfigMapBuilder) getNodeIdentities() []string {
+	if b.ClusterConfig.Tls == nil {
+		return nil
+	}
+	return []string{security.NodeIdentity}
+}
+
+// getConfigOverrides returns the `configOverrides` of the given file.
+func (b *NifiConfigMapBuilder) getConfigOverrides(file string) map[string]string {
+	if b.Overrides == nil {
+		return nil
+	}
+	return b.Overrides.ConfigOverrides[file]
+}
+
 // useZooKeeperStateProvider returns true when a ZooKeeper configmap is configured.
 func (b *NifiConfigMapBuilder) useZooKeeperStateProvider() bool {
 	return b.ClusterConfig.ZookeeperConfigMapName != nil &&
@@ -142,8 +225,8 @@ func (b *NifiConfigMapBuilder) getStateManagementConfig() string {
 		// Kubernetes-native clustering (NiFi 2.x only):
 		// Use KubernetesConfigMapStateProvider which stores cluster state in Kubernetes
 		// ConfigMaps — the only CLUSTER-scope provider available without ZooKeeper.
-		// NiFi 1.x does not ship this provider and must use ZooKeeper instead.
-		// TODO: validate that product version is 2.x when this path is taken.
+		// NiFi 1.x does not ship this provider and must use ZooKeeper instead,
+		// the cluster reconciler refuses to roll out 1.x without ZooKeeper.
 		clusterProviderBlock = `
 	<cluster-provider>
 		<id>kubernetes-provider</id>
@@ -160,7 +243,7 @@ func (b *NifiConfigMapBuilder) getStateManagementConfig() string {
 	return util.IndentTab4Spaces(xml)
 }
 
-func (b *NifiConfigMapBuilder) getBootstrapConfig() string {
+func (b *NifiConfigMapBuilder) getBootstrapConfig() (string, error) {
 	config := map[string]string{
 		"java":                      "java",
 		"run.as":                    "",
@@ -170,8 +253,6 @@ func (b *NifiConfigMapBuilder) getBootstrapConfig() string {
 		"graceful.shutdown.seconds": b.Config.GracefulShutdownTimeout,
 	}
 
-	// TODO: add jvm args, Pay attention to the order of map traversal
-
 	keys := slices.Sorted(maps.Keys(config))
 
 	data := ""
@@ -180,7 +261,17 @@ func (b *NifiConfigMapBuilder) getBootstrapConfig() string {
 		data += fmt.Sprintf("%s=%s\n", key, config[key])
 	}
 
-	return data
+	// java.arg.N are appended in argument order instead of being sorted with the other keys,
+	// so the generated file only changes when the arguments do.
+	jvmArgs, err := getJVMArguments(b.Config, b.JVMArgumentOverrides)
+	if err != nil {
+		return "", err
+	}
+	for i, arg := range jvmArgs {
+		data += fmt.Sprintf("java.arg.%d=%s\n", i+1, arg)
+	}
+
+	return data, nil
 }
 
 func (b *NifiConfigMapBuilder) getNifiProperties(ctx context.Context) (string, error) {
@@ -262,6 +353,8 @@ func (b *NifiConfigMapBuilder) getNifiProperties(ctx context.Context) (string, e
 	properties.Add("nifi.content.claim.max.appendable.size", "1 MB")
 	// nifi.content.repository.directory.default
 	properties.Add("nifi.content.repository.directory.default", NifiRepositoryMouhtPath["content"])
+	// nifi.content.repository.directory.<name>
+	b.addRepositoryDirectories(properties, "content")
 	// nifi.content.repository.archive.max.retention.period
 	properties.Add("nifi.content.repository.archive.max.retention.period", "")
 	// nifi.content.repository.archive.max.usage.percentage
@@ -278,11 +371,12 @@ func (b *NifiConfigMapBuilder) getNifiProperties(ctx context.Context) (string, e
 	properties.Add("nifi.provenance.repository.implementation", "org.apache.nifi.provenance.WriteAheadProvenanceRepository")
 	// nifi.provenance.repository.directory.default
 	properties.Add("nifi.provenance.repository.directory.default", NifiRepositoryMouhtPath["provenance"])
+	// nifi.provenance.repository.directory.<name>
+	b.addRepositoryDirectories(properties, "provenance")
 	// nifi.provenance.repository.max.storage.time
 	properties.Add("nifi.provenance.repository.max.storage.time", "")
-	// TODO: add nifi.provenance.repository.max.storage.size support
 	// nifi.provenance.repository.max.storage.size
-	// properties.Add("nifi.provenance.repository.max.storage.size", "")
+	properties.Add("nifi.provenance.repository.max.storage.size", b.getProvenanceMaxStorageSize())
 	// nifi.provenance.repository.rollover.time
 	properties.Add("nifi.provenance.repository.rollover.time", "10 min")
 	// nifi.provenance.repository.rollover.size
@@ -338,14 +432,14 @@ func (b *NifiConfigMapBuilder) getNifiProperties(ctx context.Context) (string, e
 		properties.Add("nifi.security.keystore", path.Join(NifiServerTlsDir, "keystore.p12"))
 		// nifi.security.keystoreType
 		properties.Add("nifi.security.keystoreType", "PKCS12")
-		// nifi.security.keystorePasswd
-		properties.Add("nifi.security.keystorePasswd", DefaultServerTlsKeyPassword)
+		// nifi.security.keystorePasswd, rendered from the store password secret by gomplate
+		properties.Add("nifi.security.keystorePasswd", security.StorePasswordTemplate)
 		// nifi.security.truststore
 		properties.Add("nifi.security.truststore", path.Join(NifiServerTlsDir, "truststore.p12"))
 		// nifi.security.truststoreType
 		properties.Add("nifi.security.truststoreType", "PKCS12")
 		// nifi.security.truststorePasswd
-		properties.Add("nifi.security.truststorePasswd", DefaultServerTlsStorePassword)
+		properties.Add("nifi.security.truststorePasswd", security.StorePasswordTemplate)
 	}
 	// nifi.web.http.host - leave blank so Jetty binds to all interfaces
 	properties.Add("nifi.web.http.host", "")
@@ -364,17 +458,27 @@ func (b *NifiConfigMapBuilder) getNifiProperties(ctx context.Context) (string, e
 	properties.Add("nifi.web.proxy.context.path", "")
 
 	// nifi.web.proxy.host
+	// NiFi validates the Host header against nifi.web.proxy.host, every Service name the UI is
+	// reached through must be allowed here. Hosts of NodePort or LoadBalancer addresses are not
+	// known to the operator and can be added with configOverrides.
+	namespace := b.Client.GetOwnerNamespace()
+	webPort := getWebPort(b.ClusterConfig).ContainerPort
+	uiServiceFQDN := fmt.Sprintf("%s.%s.svc.cluster.local", UIServiceName(b.ClusterName, b.RoleName), namespace)
+	proxyHosts := []string{
+		UIServiceName(b.ClusterName, b.RoleName),
+		uiServiceFQDN,
+		fmt.Sprintf("%s:%d", uiServiceFQDN, webPort),
+	}
 	// For NiFi 1.x with the PrometheusReportingTask Job enabled, the Job connects
-	// to NiFi via a dedicated Service whose FQDN differs from the pod's NODE_ADDRESS.
-	// NiFi validates the Host header against nifi.web.proxy.host, so we must allow
-	// that FQDN here — consistent with Stackable Rust operator's get_proxy_hosts().
+	// to NiFi via a dedicated Service whose FQDN differs from the pod's NODE_ADDRESS,
+	// consistent with Stackable Rust operator's get_proxy_hosts().
 	if b.Image != nil && strings.HasPrefix(b.Image.ProductVersion, "1.") &&
 		b.ClusterConfig.CreateReportingTaskJob != nil && b.ClusterConfig.CreateReportingTaskJob.Enable {
-		namespace := b.Client.GetOwnerNamespace()
 		reportingTaskFQDN := fmt.Sprintf("%s-reporting-task.%s.svc.cluster.local:%d",
 			b.ClusterName, namespace, getPort("https"))
-		properties.Add("nifi.web.proxy.host", reportingTaskFQDN)
+		proxyHosts = append(proxyHosts, reportingTaskFQDN)
 	}
+	properties.Add("nifi.web.proxy.host", strings.Join(proxyHosts, ","))
 
 	// nifi.sensitive.props.key
 	properties.Add("nifi.sensitive.props.key", fmt.Sprintf("${file:UTF-8:%s}", path.Join(constants.KubedoopRoot, "sensitiveproperty", "nifiSensitivePropsKey")))
@@ -387,12 +491,17 @@ func (b *NifiConfigMapBuilder) getNifiProperties(ctx context.Context) (string, e
 	// security properties
 	// nifi.administrative.yield.duration
 	properties.Add("nifi.administrative.yield.duration", "30 sec")
-	properties.Add("nifi.authorizer.configuration.file", path.Join(NifiConfigDir, "authorizers.xml"))
+	properties.Add("nifi.authorizer.configuration.file", path.Join(NifiConfigDir, AuthorizersFile))
 	properties.Add("nifi.login.identity.provider.configuration.file", path.Join(NifiConfigDir, "login-identity-providers.xml"))
 	// nifi.security.user.login.identity.provider
-	properties.Add("nifi.security.user.login.identity.provider", "login-identity-provider")
+	// Jetty requires a client certificate when no login identity provider is configured.
+	if b.Authentication != nil && !b.Authentication.HasLoginIdentityProvider() {
+		properties.Add("nifi.security.user.login.identity.provider", "")
+	} else {
+		properties.Add("nifi.security.user.login.identity.provider", security.LoginIdentityProviderIdentifier)
+	}
 	// nifi.security.user.authorizer
-	properties.Add("nifi.security.user.authorizer", "authorizer")
+	properties.Add("nifi.security.user.authorizer", security.AuthorizerIdentifier)
 	// nifi.security.allow.anonymous.authentication
 	properties.Add("nifi.security.allow.anonymous.authentication", "false")
 	// nifi cluster mode
@@ -400,8 +509,8 @@ func (b *NifiConfigMapBuilder) getNifiProperties(ctx context.Context) (string, e
 		// Kubernetes-native clustering (NiFi 2.x only):
 		// No ZooKeeper — nil or empty ZookeeperConfigMapName means k8s-native mode.
 		// Use KubernetesLeaderElectionManager for leader election.
-		// NiFi 1.x does not support this mode and requires ZooKeeper.
-		// TODO: validate product version is 2.x when this path is taken.
+		// NiFi 1.x does not support this mode and requires ZooKeeper,
+		// the cluster reconciler refuses to roll out 1.x without ZooKeeper.
 		if enableTls {
 			properties.Add("nifi.cluster.protocol.is.secure", "true")
 		} else {
@@ -435,7 +544,7 @@ func (b *NifiConfigMapBuilder) getNifiProperties(ctx context.Context) (string, e
 	}
 
 	if b.ClusterConfig.Authentication != nil {
-		auth, error := security.NewAuthentication(ctx, b.Client, b.ClusterName, b.ClusterConfig.Authentication)
+		auth, error := security.NewAuthentication(ctx, b.Client, b.ClusterName, b.ClusterConfig.Authentication, b.ClusterConfig.Tls)
 		if error != nil {
 			return "", fmt.Errorf("failed to create authentication: %w", error)
 		}
@@ -449,6 +558,8 @@ func (b *NifiConfigMapBuilder) getNifiProperties(ctx context.Context) (string, e
 		}
 	}
 
+	applyPropertiesOverrides(properties, b.getConfigOverrides(NifiPropertiesFile))
+
 	data, err := properties.Marshal()
 	if err != nil {
 		return "", err
@@ -457,6 +568,34 @@ func (b *NifiConfigMapBuilder) getNifiProperties(ctx context.Context) (string, e
 	return data, nil
 }
 
+func (b *NifiConfigMapBuilder) getStorage() *nifiv1alpha1.StorageSpec {
+	if b.Config == nil {
+		return nil
+	}
+	return b.Config.Storage
+}
+
+// addRepositoryDirectories registers the additional, named directories of the content or
+// provenance repository, sorted by name to keep the generated file stable.
+func (b *NifiConfigMapBuilder) addRepositoryDirectories(properties *properties.Properties, repository string) {
+	directories := getRepositoryDirectoryPaths(b.getStorage(), repository)
+	for _, name := range slices.Sorted(maps.Keys(directories)) {
+		properties.Add(fmt.Sprintf("nifi.%s.repository.directory.%s", repository, name), directories[name])
+	}
+}
+
+// getProvenanceMaxStorageSize limits the provenance repository to 90% of its volumes,
+// NiFi only starts purging events once the limit is exceeded.
+// The limit applies to all provenance directories together.
+func (b *NifiConfigMapBuilder) getProvenanceMaxStorageSize() string {
+	storage := b.getStorage()
+	capacity := getRepositoryCapacity(storage, "provenance")
+	for _, s := range getRepositoryDirectories(storage, "provenance") {
+		capacity.Add(capacityOrDefault(&s, "provenance"))
+	}
+	return fmt.Sprintf("%d MB", capacity.Value()*9/10/(1024*1024))
+}
+
 func NewConfigReconciler(
 	client *client.Client,
 	clusterConfig *nifiv1alpha1.ClusterConfigSpec,
@@ -464,6 +603,8 @@ func NewConfigReconciler(
 	roleGroupInfo reconciler.RoleGroupInfo,
 	config *nifiv1alpha1.ConfigSpec,
 	authentication *security.Authentication,
+	jvmArgumentOverrides []*nifiv1alpha1.JVMArgumentOverridesSpec,
+	overrides *commonsv1alpha1.OverridesSpec,
 ) *reconciler.SimpleResourceReconciler[builder.ConfigBuilder] {
 
 	nifiConfigSecretBuilder := NewNifiConfigBuilder(
@@ -473,6 +614,8 @@ func NewConfigReconciler(
 		roleGroupInfo,
 		config,
 		authentication,
+		jvmArgumentOverrides,
+		overrides,
 	)
 
 	return reconciler.NewSimpleResourceReconciler[builder.ConfigBuilder](
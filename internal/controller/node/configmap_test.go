@@ -0,0 +1,41 @@
+package node
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+	"github.com/zncdatadev/nifi-operator/internal/common/security"
+)
+
+func TestNifiConfigMapBuilder_OpaAuthorizers(t *testing.T) {
+	opa := &nifiv1alpha1.AuthorizationSpec{Opa: &nifiv1alpha1.OpaSpec{ConfigMapName: "opa", Package: "kubedoop.nifi"}}
+	b := &NifiConfigMapBuilder{
+		ClusterConfig: &nifiv1alpha1.ClusterConfigSpec{
+			Authentication: []nifiv1alpha1.AuthenticationSpec{{AuthenticationClass: "ldap"}},
+			Authorization:  opa,
+		},
+		Authentication: &security.Authentication{},
+	}
+
+	authorizers, err := b.getAuthorizersConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"<class>" + security.OpaAuthorizerClass + "</class>",
+		`<property name="OPA_URI">{{ getenv "` + security.OpaBaseURLEnvName + `" }}</property>`,
+		`<property name="OPA_RULE_HEAD">kubedoop/nifi/allow</property>`,
+	} {
+		if !strings.Contains(authorizers, expected) {
+			t.Errorf("expected %q in:\n%s", expected, authorizers)
+		}
+	}
+
+	// Without authentication authorizers.xml is not generated, OPA would be silently ignored.
+	b.ClusterConfig.Authentication = nil
+	if _, err := b.Build(context.Background()); err == nil || !strings.Contains(err.Error(), "requires clusterConfig.authentication") {
+		t.Errorf("expected OPA authorization without authentication to fail, got %v", err)
+	}
+}
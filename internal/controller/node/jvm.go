@@ -0,0 +1,116 @@
+package node
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+// DefaultMemoryLimit is the container memory the heap is derived from when the
+// role group does not set `resources.memory.limit`.
+var DefaultMemoryLimit = resource.MustParse("4Gi")
+
+// heapMemoryPercent is the share of the container memory limit given to the JVM heap,
+// the rest is left to metaspace, thread stacks, direct buffers and the OS page cache.
+// NiFi uses a lot of off-heap memory, a larger share gets the container OOMKilled.
+const heapMemoryPercent = 50
+
+// minHeapMegabytes keeps NiFi startable when the memory limit is very small.
+const minHeapMegabytes = 512
+
+// securityPropertiesFile overrides the JVM security properties, see getSecurityProperties.
+const securityPropertiesFile = "security.properties"
+
+// getHeapMegabytes returns the JVM heap size in MiB derived from the memory limit.
+func getHeapMegabytes(config *nifiv1alpha1.ConfigSpec) int64 {
+	limit := DefaultMemoryLimit
+	if config != nil && config.RoleGroupConfigSpec != nil && config.Resources != nil &&
+		config.Resources.Memory != nil && !config.Resources.Memory.Limit.IsZero() {
+		limit = config.Resources.Memory.Limit
+	}
+
+	heap := limit.Value() * heapMemoryPercent / 100 / (1024 * 1024)
+	return max(heap, minHeapMegabytes)
+}
+
+// getDefaultJVMArguments returns the JVM arguments generated by the operator, in a fixed order.
+func getDefaultJVMArguments(config *nifiv1alpha1.ConfigSpec) []string {
+	heap := getHeapMegabytes(config)
+
+	return []string{
+		// Same initial and maximum heap, NiFi allocates most of it at startup anyway.
+		fmt.Sprintf("-Xms%dm", heap),
+		fmt.Sprintf("-Xmx%dm", heap),
+		"-XX:+UseG1GC",
+		"-XX:+ExitOnOutOfMemoryError",
+		"-Djava.awt.headless=true",
+		"-Djava.net.preferIPv4Stack=true",
+		"-Djava.protocol.handler.pkgs=sun.net.www.protocol",
+		"-Dsun.net.http.allowRestrictedHeaders=true",
+		"-Djavax.security.auth.useSubjectCredsOnly=true",
+		"-Dcurator-log-only-first-connection-issue-as-error-level=true",
+		"-Djava.security.properties=" + path.Join(NifiConfigDir, securityPropertiesFile),
+	}
+}
+
+// getSecurityProperties returns the JVM security properties.
+// Pod IPs change on every restart, so DNS lookups must not be cached forever.
+func getSecurityProperties() string {
+	return "networkaddress.cache.ttl=30\n" +
+		"networkaddress.cache.negative.ttl=0\n"
+}
+
+// getJVMArguments returns the default JVM arguments with the overrides applied in order,
+// the role overrides first and the role group overrides last.
+func getJVMArguments(config *nifiv1alpha1.ConfigSpec, overrides []*nifiv1alpha1.JVMArgumentOverridesSpec) ([]string, error) {
+	args := getDefaultJVMArguments(config)
+
+	for _, override := range overrides {
+		var err error
+		if args, err = applyJVMArgumentOverrides(args, override); err != nil {
+			return nil, err
+		}
+	}
+
+	return args, nil
+}
+
+// applyJVMArgumentOverrides removes the arguments listed in `remove`, then the arguments fully
+// matching one of the `removeRegex` expressions, and appends the arguments listed in `add`.
+// An added argument which is already present is not duplicated.
+func applyJVMArgumentOverrides(args []string, override *nifiv1alpha1.JVMArgumentOverridesSpec) ([]string, error) {
+	if override == nil {
+		return args, nil
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(override.RemoveRegex))
+	for _, expr := range override.RemoveRegex {
+		pattern, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid jvmArgumentOverrides.removeRegex %q: %w", expr, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	result := slices.DeleteFunc(slices.Clone(args), func(arg string) bool {
+		if slices.Contains(override.Remove, arg) {
+			return true
+		}
+		return slices.ContainsFunc(patterns, func(p *regexp.Regexp) bool {
+			return p.MatchString(arg)
+		})
+	})
+
+	for _, arg := range override.Add {
+		if !slices.Contains(result, arg) {
+			result = append(result, arg)
+		}
+	}
+
+	return result, nil
+}
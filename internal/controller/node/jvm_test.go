@@ -0,0 +1,135 @@
+package node
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func configWithMemory(limit string) *nifiv1alpha1.ConfigSpec {
+	return &nifiv1alpha1.ConfigSpec{
+		RoleGroupConfigSpec: &commonsv1alpha1.RoleGroupConfigSpec{
+			Resources: &commonsv1alpha1.ResourcesSpec{
+				Memory: &commonsv1alpha1.MemoryResource{Limit: resource.MustParse(limit)},
+			},
+		},
+	}
+}
+
+func TestGetHeapMegabytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *nifiv1alpha1.ConfigSpec
+		expected int64
+	}{
+		{name: "default limit", config: nil, expected: 2048},
+		{name: "memory limit", config: configWithMemory("2Gi"), expected: 1024},
+		{name: "minimum heap", config: configWithMemory("256Mi"), expected: minHeapMegabytes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getHeapMegabytes(tt.config); got != tt.expected {
+				t.Errorf("expected %d MiB heap, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetJVMArguments_Defaults(t *testing.T) {
+	args, err := getJVMArguments(configWithMemory("2Gi"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if args[0] != "-Xms1024m" || args[1] != "-Xmx1024m" {
+		t.Errorf("expected heap arguments first, got %v", args[:2])
+	}
+	for _, expected := range []string{"-XX:+UseG1GC", "-Djava.security.properties=" + NifiConfigDir + "/security.properties"} {
+		if !slices.Contains(args, expected) {
+			t.Errorf("expected %q in %v", expected, args)
+		}
+	}
+}
+
+func TestGetJVMArguments_Overrides(t *testing.T) {
+	role := &nifiv1alpha1.JVMArgumentOverridesSpec{
+		Remove:      []string{"-XX:+UseG1GC"},
+		RemoveRegex: []string{"-Xm[sx].*"},
+		Add:         []string{"-Xms1g", "-Xmx1g", "-XX:+UseZGC"},
+	}
+	roleGroup := &nifiv1alpha1.JVMArgumentOverridesSpec{
+		// The role group sees the arguments produced by the role overrides.
+		Remove: []string{"-XX:+UseZGC"},
+		// Full match only, -Djava.awt.headless=true is kept.
+		RemoveRegex: []string{"-Djava\\.awt"},
+		Add:         []string{"-Dfoo=bar", "-Xmx1g"},
+	}
+
+	args, err := getJVMArguments(nil, []*nifiv1alpha1.JVMArgumentOverridesSpec{role, nil, roleGroup})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defaults := getDefaultJVMArguments(nil)
+	expected := slices.DeleteFunc(slices.Clone(defaults), func(arg string) bool {
+		return strings.HasPrefix(arg, "-Xm") || arg == "-XX:+UseG1GC"
+	})
+	expected = append(expected, "-Xms1g", "-Xmx1g", "-Dfoo=bar")
+
+	if !slices.Equal(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+}
+
+func TestGetJVMArguments_InvalidRegex(t *testing.T) {
+	_, err := getJVMArguments(nil, []*nifiv1alpha1.JVMArgumentOverridesSpec{{RemoveRegex: []string{"-Xm("}}})
+	if err == nil {
+		t.Fatal("expected an error for an invalid removeRegex")
+	}
+}
+
+func TestGetBootstrapConfig(t *testing.T) {
+	b := &NifiConfigMapBuilder{
+		Config: configWithMemory("2Gi"),
+		JVMArgumentOverrides: []*nifiv1alpha1.JVMArgumentOverridesSpec{
+			{Add: []string{"-Dfoo=bar"}},
+		},
+	}
+
+	data, err := b.getBootstrapConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	var jvmArgs []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "java.arg.") {
+			jvmArgs = append(jvmArgs, line)
+		}
+	}
+
+	defaults := getDefaultJVMArguments(b.Config)
+	if len(jvmArgs) != len(defaults)+1 {
+		t.Fatalf("expected %d java.arg entries, got %d", len(defaults)+1, len(jvmArgs))
+	}
+	if jvmArgs[0] != "java.arg.1=-Xms1024m" {
+		t.Errorf("expected java.arg.1=-Xms1024m, got %s", jvmArgs[0])
+	}
+	if expected := fmt.Sprintf("java.arg.%d=-Dfoo=bar", len(jvmArgs)); jvmArgs[len(jvmArgs)-1] != expected {
+		t.Errorf("expected %s, got %s", expected, jvmArgs[len(jvmArgs)-1])
+	}
+
+	// The output must not depend on map iteration order.
+	again, _ := b.getBootstrapConfig()
+	if again != data {
+		t.Error("expected a stable bootstrap.conf")
+	}
+}
@@ -0,0 +1,116 @@
+package node
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/productlogging"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+const (
+	LogbackFile = "logback.xml"
+
+	// LoggingConfigHashAnnotation is set on the pod template, so a change of the logging config rolls out the pods.
+	LoggingConfigHashAnnotation = "nifi.kubedoop.dev/logging-config-hash"
+
+	// nifiLogFile is the log file of NiFi in the log directory of the container, the `.log4j.xml` suffix
+	// selects the parser of the Vector agent for the XML layout written by logback.
+	nifiLogFile = "nifi.log4j.xml"
+)
+
+// defaultLoggerLevels are the levels of the logback.xml shipped with NiFi, which silences noisy libraries.
+var defaultLoggerLevels = map[string]string{
+	"org.apache.nifi":            "INFO",
+	"org.apache.nifi.processors": "WARN",
+	"org.apache.curator":         "WARN",
+	"org.eclipse.jetty":          "WARN",
+	"org.springframework":        "WARN",
+	"org.apache.zookeeper":       "ERROR",
+	"org.apache.nifi.controller.repository.StandardProcessSession": "ERROR",
+}
+
+// toLogbackLevel maps the commons log levels to logback, which has no FATAL.
+func toLogbackLevel(level string) string {
+	if level == "FATAL" {
+		return "ERROR"
+	}
+	return level
+}
+
+// getLoggingConfigSpec returns the logging config of the container, nil if it is not configured.
+func getLoggingConfigSpec(roleGroupConfig *nifiv1alpha1.ConfigSpec, containerName string) *commonsv1alpha1.LoggingConfigSpec {
+	if roleGroupConfig == nil || roleGroupConfig.RoleGroupConfigSpec == nil || roleGroupConfig.Logging == nil {
+		return nil
+	}
+	spec, ok := roleGroupConfig.Logging.Containers[containerName]
+	if !ok {
+		return nil
+	}
+	return &spec
+}
+
+// getLogbackLoggingConfigSpec returns the logging config of the container on top of defaultLoggerLevels,
+// with the levels mapped to logback.
+func getLogbackLoggingConfigSpec(roleGroupConfig *nifiv1alpha1.ConfigSpec, containerName string) *commonsv1alpha1.LoggingConfigSpec {
+	spec := &commonsv1alpha1.LoggingConfigSpec{Loggers: map[string]*commonsv1alpha1.LogLevelSpec{}}
+	for name, level := range defaultLoggerLevels {
+		spec.Loggers[name] = &commonsv1alpha1.LogLevelSpec{Level: level}
+	}
+
+	if configured := getLoggingConfigSpec(roleGroupConfig, containerName); configured != nil {
+		if configured.Console != nil && configured.Console.Level != "" {
+			spec.Console = &commonsv1alpha1.LogLevelSpec{Level: toLogbackLevel(configured.Console.Level)}
+		}
+		if configured.File != nil && configured.File.Level != "" {
+			spec.File = &commonsv1alpha1.LogLevelSpec{Level: toLogbackLevel(configured.File.Level)}
+		}
+		for name, level := range configured.Loggers {
+			if level == nil || level.Level == "" {
+				continue
+			}
+			spec.Loggers[name] = &commonsv1alpha1.LogLevelSpec{Level: toLogbackLevel(level.Level)}
+		}
+	}
+	return spec
+}
+
+// getLogbackConfig returns logback.xml for the container, generated like the other products of the platform:
+// a console appender and a rolling file appender writing nifi.log4j.xml to the log directory of the container.
+// The loggers of `logging.containers.<container>.loggers` override the NiFi defaults, `ROOT` sets the level of
+// the root logger. The console and file levels are thresholds of the appenders.
+func getLogbackConfig(roleGroupConfig *nifiv1alpha1.ConfigSpec, containerName string) (string, error) {
+	generator, err := productlogging.NewConfigGenerator(
+		getLogbackLoggingConfigSpec(roleGroupConfig, containerName),
+		containerName,
+		nifiLogFile,
+		productlogging.LogTypeLogback,
+	)
+	if err != nil {
+		return "", err
+	}
+	return generator.Content()
+}
+
+// getLogVolumeSizeLimit returns the size limit of the log volume, large enough for the log file and its backup.
+func getLogVolumeSizeLimit() resource.Quantity {
+	maxFileSize := resource.NewQuantity(int64(productlogging.DefaultRotatingFileHandlerMaxBytes)*2, resource.BinarySI)
+	return productlogging.CalculateLogVolumeSizeLimit([]resource.Quantity{*maxFileSize})
+}
+
+// getLoggingConfigHash returns the hash of the logging config of logback.xml, it is set on the pod template with
+// LoggingConfigHashAnnotation. The generated logback.xml lists the loggers in map order, so the config is hashed
+// instead, encoding/json sorts the loggers by name.
+func getLoggingConfigHash(roleGroupConfig *nifiv1alpha1.ConfigSpec, containerName string) (string, error) {
+	spec, err := json.Marshal(getLogbackLoggingConfigSpec(roleGroupConfig, containerName))
+	if err != nil {
+		return "", fmt.Errorf("failed to hash the logging config of %s: %w", LogbackFile, err)
+	}
+	sum := sha256.Sum256(spec)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,113 @@
+package node
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func configWithLogging(containers map[string]commonsv1alpha1.LoggingConfigSpec) *nifiv1alpha1.ConfigSpec {
+	return &nifiv1alpha1.ConfigSpec{RoleGroupConfigSpec: &commonsv1alpha1.RoleGroupConfigSpec{
+		Logging: &commonsv1alpha1.LoggingSpec{Containers: containers},
+	}}
+}
+
+func TestGetLogbackConfig_Defaults(t *testing.T) {
+	got, err := getLogbackConfig(nil, "node")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		`<root level="INFO">`,
+		"<File>/kubedoop/log/node/nifi.log4j.xml</File>",
+		"<FileNamePattern>/kubedoop/log/node/nifi.log4j.xml.%i</FileNamePattern>",
+		"<MaxFileSize>10MB</MaxFileSize>",
+		`<layout class="ch.qos.logback.classic.log4j.XMLLayout" />`,
+		`<logger name="org.apache.zookeeper" level="ERROR" />`,
+		`<logger name="org.apache.nifi.processors" level="WARN" />`,
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+
+	var document struct{}
+	if err := xml.Unmarshal([]byte(got), &document); err != nil {
+		t.Errorf("expected well-formed XML: %v", err)
+	}
+}
+
+func TestGetLogbackConfig_Loggers(t *testing.T) {
+	config := configWithLogging(map[string]commonsv1alpha1.LoggingConfigSpec{
+		"node": {
+			Loggers: map[string]*commonsv1alpha1.LogLevelSpec{
+				"ROOT":                         {Level: "WARN"},
+				"org.apache.nifi.processors":   {Level: "DEBUG"},
+				"org.apache.nifi.web.security": {Level: "FATAL"},
+				"com.example":                  {Level: "TRACE"},
+			},
+			Console: &commonsv1alpha1.LogLevelSpec{Level: "ERROR"},
+			File:    &commonsv1alpha1.LogLevelSpec{Level: "DEBUG"},
+		},
+		"vector": {
+			Loggers: map[string]*commonsv1alpha1.LogLevelSpec{"org.ignored": {Level: "DEBUG"}},
+		},
+	})
+
+	got, err := getLogbackConfig(config, "node")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		`<root level="WARN">`,
+		`<logger name="org.apache.nifi.processors" level="DEBUG" />`,
+		// logback has no FATAL.
+		`<logger name="org.apache.nifi.web.security" level="ERROR" />`,
+		`<logger name="com.example" level="TRACE" />`,
+		"<level>ERROR</level>",
+		"<level>DEBUG</level>",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+	if strings.Contains(got, "org.ignored") {
+		t.Errorf("expected the loggers of other containers to be ignored:\n%s", got)
+	}
+}
+
+func TestGetLoggingConfigHash(t *testing.T) {
+	debug := configWithLogging(map[string]commonsv1alpha1.LoggingConfigSpec{
+		"node": {Loggers: map[string]*commonsv1alpha1.LogLevelSpec{"org.apache.nifi.processors": {Level: "DEBUG"}}},
+	})
+
+	defaultHash, err := getLoggingConfigHash(nil, "node")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	debugHash, err := getLoggingConfigHash(debug, "node")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	againHash, _ := getLoggingConfigHash(debug, "node")
+
+	if defaultHash == debugHash {
+		t.Error("expected a logger level change to change the hash")
+	}
+	if debugHash != againHash {
+		t.Error("expected the hash to be stable")
+	}
+}
+
+func TestGetLogVolumeSizeLimit(t *testing.T) {
+	// The log file of 10Mi and its backup, times 3.
+	if got := getLogVolumeSizeLimit(); got.Value() != 60*1024*1024 {
+		t.Errorf("expected 60Mi, got %s", got.String())
+	}
+}
@@ -0,0 +1,232 @@
+package node
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/zncdatadev/operator-go/pkg/config/properties"
+)
+
+// Config files generated by the operator, they are the keys accepted in `configOverrides`.
+const (
+	BootstrapConfFile          = "bootstrap.conf"
+	NifiPropertiesFile         = "nifi.properties"
+	LoginIdentityProvidersFile = "login-identity-providers.xml"
+	AuthorizersFile            = "authorizers.xml"
+	StateManagementFile        = "state-management.xml"
+)
+
+// Seed files of the users and policies, copied to the database repository when NiFi has not created them yet.
+const (
+	InitialUsersFile          = "initial-users.xml"
+	InitialAuthorizationsFile = "initial-authorizations.xml"
+)
+
+// applyPropertiesOverrides sets the overridden keys, the generated value of an existing key is replaced.
+func applyPropertiesOverrides(props *properties.Properties, overrides map[string]string) {
+	for _, key := range slices.Sorted(maps.Keys(overrides)) {
+		props.Add(key, overrides[key])
+	}
+}
+
+// applyKeyValueOverrides overrides the `key=value` lines of data. Existing keys are replaced in place,
+// new keys are appended sorted by name.
+func applyKeyValueOverrides(data string, overrides map[string]string) string {
+	if len(overrides) == 0 {
+		return data
+	}
+
+	pending := maps.Clone(overrides)
+	lines := strings.SplitAfter(data, "\n")
+	for i, line := range lines {
+		key, _, found := strings.Cut(strings.TrimSuffix(line, "\n"), "=")
+		if !found {
+			continue
+		}
+		if value, ok := pending[key]; ok {
+			lines[i] = fmt.Sprintf("%s=%s\n", key, value)
+			delete(pending, key)
+		}
+	}
+
+	result := strings.Join(lines, "")
+	if result != "" && !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+	for _, key := range slices.Sorted(maps.Keys(pending)) {
+		result += fmt.Sprintf("%s=%s\n", key, pending[key])
+	}
+	return result
+}
+
+// xmlEdit replaces data[start:end] with text.
+type xmlEdit struct {
+	start, end int
+	text       string
+}
+
+// xmlProvider is a provider element of a NiFi XML config file, e.g. `<provider>` in
+// login-identity-providers.xml or `<cluster-provider>` in state-management.xml.
+type xmlProvider struct {
+	id string
+	// indent is the indentation of the child elements.
+	indent string
+	// properties maps the property names to the offsets of their `<property>` element.
+	properties map[string][2]int
+	// end is the offset of the closing tag.
+	end int
+}
+
+// applyXMLPropertyOverrides overrides the `<property name="...">` elements of the providers in a
+// NiFi XML config file. The keys of overrides are `<provider id>.<property name>`, the provider is
+// matched by its `<identifier>` or `<id>` element. Existing properties are replaced, new ones are
+// appended to the provider.
+//
+// The file is edited in place instead of being re-encoded, so the gomplate expressions it contains
+// are kept as they are.
+func applyXMLPropertyOverrides(data string, overrides map[string]string) (string, error) {
+	if len(overrides) == 0 {
+		return data, nil
+	}
+
+	providerOverrides := make(map[string]map[string]string)
+	for key, value := range overrides {
+		id, name, found := strings.Cut(key, ".")
+		if !found || id == "" || name == "" {
+			return "", fmt.Errorf("invalid XML config override %q, expected `<provider id>.<property name>`", key)
+		}
+		if providerOverrides[id] == nil {
+			providerOverrides[id] = make(map[string]string)
+		}
+		providerOverrides[id][name] = value
+	}
+
+	providers, err := parseXMLProviders(data)
+	if err != nil {
+		return "", err
+	}
+
+	var edits []xmlEdit
+	for _, id := range slices.Sorted(maps.Keys(providerOverrides)) {
+		provider, ok := providers[id]
+		if !ok {
+			return "", fmt.Errorf("XML config override references unknown provider %q", id)
+		}
+
+		added := ""
+		for _, name := range slices.Sorted(maps.Keys(providerOverrides[id])) {
+			element := renderXMLProperty(name, providerOverrides[id][name])
+			if offsets, exists := provider.properties[name]; exists {
+				edits = append(edits, xmlEdit{start: offsets[0], end: offsets[1], text: element})
+				continue
+			}
+			added += provider.indent + element + "\n"
+		}
+		if added != "" {
+			// Insert the new properties on their own lines, right before the line of the closing tag.
+			insertAt := provider.end
+			if lineStart := strings.LastIndex(data[:provider.end], "\n") + 1; strings.TrimSpace(data[lineStart:provider.end]) == "" {
+				insertAt = lineStart
+			} else {
+				added = "\n" + added
+			}
+			edits = append(edits, xmlEdit{start: insertAt, end: insertAt, text: added})
+		}
+	}
+
+	// Apply the edits from the end of the file, so the offsets of the remaining edits stay valid.
+	slices.SortFunc(edits, func(a, b xmlEdit) int { return b.start - a.start })
+	for _, edit := range edits {
+		data = data[:edit.start] + edit.text + data[edit.end:]
+	}
+	return data, nil
+}
+
+// parseXMLProviders returns the children of the root element which have an `<identifier>` or `<id>`, keyed by id.
+func parseXMLProviders(data string) (map[string]*xmlProvider, error) {
+	providers := make(map[string]*xmlProvider)
+
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	depth := 0
+	var current *xmlProvider
+	var property string
+	var propertyStart int
+	var text strings.Builder
+
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XML config: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch depth {
+			case 2:
+				current = &xmlProvider{properties: make(map[string][2]int)}
+			case 3:
+				if current.indent == "" {
+					lineStart := strings.LastIndex(data[:offset], "\n") + 1
+					current.indent = data[lineStart:offset]
+				}
+				text.Reset()
+				property = ""
+				if t.Name.Local == "property" {
+					for _, attr := range t.Attr {
+						if attr.Name.Local == "name" {
+							property = attr.Value
+						}
+					}
+					propertyStart = offset
+				}
+			}
+		case xml.CharData:
+			if depth == 3 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch depth {
+			case 2:
+				current.end = offset
+				if current.id != "" {
+					providers[current.id] = current
+				}
+				current = nil
+			case 3:
+				switch {
+				case t.Name.Local == "identifier" || t.Name.Local == "id":
+					current.id = strings.TrimSpace(text.String())
+				case t.Name.Local == "property" && property != "":
+					current.properties[property] = [2]int{propertyStart, int(decoder.InputOffset())}
+				}
+			}
+			depth--
+		}
+	}
+
+	return providers, nil
+}
+
+func renderXMLProperty(name, value string) string {
+	return fmt.Sprintf(`<property name="%s">%s</property>`, escapeXML(name, true), escapeXML(value, false))
+}
+
+// escapeXML escapes the markup characters only, quotes are kept in text so gomplate
+// expressions like `{{ getenv "NAME" }}` still work.
+func escapeXML(s string, attribute bool) string {
+	replacements := []string{"&", "&amp;", "<", "&lt;", ">", "&gt;"}
+	if attribute {
+		replacements = append(replacements, `"`, "&quot;")
+	}
+	return strings.NewReplacer(replacements...).Replace(s)
+}
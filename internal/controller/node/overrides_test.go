@@ -0,0 +1,96 @@
+package node
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zncdatadev/operator-go/pkg/config/properties"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func TestApplyKeyValueOverrides(t *testing.T) {
+	data := "conf.dir=./conf\ngraceful.shutdown.seconds=30s\njava.arg.1=-Xms1g\n"
+
+	got := applyKeyValueOverrides(data, map[string]string{
+		"java.arg.1":                "-Xms2g",
+		"run.as":                    "nifi",
+		"graceful.shutdown.seconds": "60",
+	})
+
+	expected := "conf.dir=./conf\ngraceful.shutdown.seconds=60\njava.arg.1=-Xms2g\nrun.as=nifi\n"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestApplyPropertiesOverrides(t *testing.T) {
+	props := properties.NewProperties()
+	props.Add("nifi.web.jetty.threads", "200")
+
+	applyPropertiesOverrides(props, map[string]string{
+		"nifi.web.jetty.threads": "400",
+		"nifi.ui.banner.text":    "prod",
+	})
+
+	if value, _ := props.Get("nifi.web.jetty.threads"); value != "400" {
+		t.Errorf("expected overridden value 400, got %q", value)
+	}
+	if value, _ := props.Get("nifi.ui.banner.text"); value != "prod" {
+		t.Errorf("expected added value prod, got %q", value)
+	}
+}
+
+func TestApplyXMLPropertyOverrides(t *testing.T) {
+	b := &NifiConfigMapBuilder{ClusterConfig: &nifiv1alpha1.ClusterConfigSpec{}}
+	data := b.getStateManagementConfig()
+
+	got, err := applyXMLPropertyOverrides(data, map[string]string{
+		"local-provider.Partitions":               "32",
+		"kubernetes-provider.ConfigMap Namespace": `{{ getenv "NAMESPACE" }}`,
+		"local-provider.Always Sync":              "a & b",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		`<property name="Partitions">32</property>`,
+		`<property name="Always Sync">a &amp; b</property>`,
+		// Added properties are indented like their siblings and keep gomplate expressions intact.
+		"\n" + indentOf(got, "<class>org.apache.nifi.kubernetes") + `<property name="ConfigMap Namespace">{{ getenv "NAMESPACE" }}</property>` + "\n",
+		// Untouched properties are kept as they are.
+		`<property name="ConfigMap Name Prefix">{{ getenv "STACKLET_NAME" }}</property>`,
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+	if strings.Contains(got, `<property name="Partitions">16</property>`) {
+		t.Errorf("expected the generated Partitions property to be replaced:\n%s", got)
+	}
+	if _, err := parseXMLProviders(got); err != nil {
+		t.Errorf("expected well-formed XML: %v", err)
+	}
+}
+
+func TestApplyXMLPropertyOverrides_Errors(t *testing.T) {
+	b := &NifiConfigMapBuilder{ClusterConfig: &nifiv1alpha1.ClusterConfigSpec{}}
+	data := b.getStateManagementConfig()
+
+	for _, overrides := range []map[string]string{
+		{"Partitions": "32"},
+		{"zk-provider.Connect String": "zk:2181"},
+	} {
+		if _, err := applyXMLPropertyOverrides(data, overrides); err == nil {
+			t.Errorf("expected an error for %v", overrides)
+		}
+	}
+}
+
+// indentOf returns the indentation of the line containing substr.
+func indentOf(data, substr string) string {
+	index := strings.Index(data, substr)
+	lineStart := strings.LastIndex(data[:index], "\n") + 1
+	return data[lineStart:index]
+}
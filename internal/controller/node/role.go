@@ -7,7 +7,6 @@ import (
 	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/builder"
 	resourceClient "github.com/zncdatadev/operator-go/pkg/client"
-	"github.com/zncdatadev/operator-go/pkg/constants"
 	"github.com/zncdatadev/operator-go/pkg/reconciler"
 	"github.com/zncdatadev/operator-go/pkg/util"
 
@@ -66,6 +65,7 @@ func (r *Reconciler) RegisterResources(ctx context.Context) error {
 			info,
 			overrides,
 			mergedConfig,
+			[]*nifiv1alpha1.JVMArgumentOverridesSpec{r.Spec.JVMArgumentOverrides, rg.JVMArgumentOverrides},
 		)
 
 		if err != nil {
@@ -76,6 +76,9 @@ func (r *Reconciler) RegisterResources(ctx context.Context) error {
 			r.AddResource(reconciler)
 		}
 	}
+
+	r.AddResource(NewUIServiceReconciler(r.Client, r.RoleInfo, r.ClusterConfig))
+
 	return nil
 }
 
@@ -85,6 +88,7 @@ func (r *Reconciler) RegisterResourceWithRoleGroup(
 	info reconciler.RoleGroupInfo,
 	overrides *commonsv1alpha1.OverridesSpec,
 	roleGroupConfig *nifiv1alpha1.ConfigSpec,
+	jvmArgumentOverrides []*nifiv1alpha1.JVMArgumentOverridesSpec,
 ) ([]reconciler.Reconciler, error) {
 	reconcilers := make([]reconciler.Reconciler, 0)
 
@@ -108,6 +112,8 @@ func (r *Reconciler) RegisterResourceWithRoleGroup(
 		info,
 		roleGroupConfig,
 		auth,
+		jvmArgumentOverrides,
+		overrides,
 	)
 
 	stsReconciler, err := NewStatefulSetReconciler(
@@ -126,13 +132,12 @@ func (r *Reconciler) RegisterResourceWithRoleGroup(
 		return nil, err
 	}
 
-	serviceReconciler := NewServiceReconciler(
+	// The headless Service resolves the pod DNS names used for node-to-node traffic.
+	serviceReconciler := NewHeadlessServiceReconciler(
 		r.Client,
 		info.GetFullName(),
 		Ports,
 		func(o *builder.ServiceBuilderOptions) {
-			o.ListenerClass = constants.ExternalUnstable
-			o.Headless = true
 			o.ClusterName = info.GetClusterName()
 			o.RoleName = info.GetRoleName()
 			o.RoleGroupName = info.GetGroupName()
@@ -168,6 +173,7 @@ func (b *Reconciler) getAuthentrication(ctx context.Context) (*security.Authenti
 		b.Client,
 		b.RoleInfo.GetClusterName(),
 		b.ClusterConfig.Authentication,
+		b.ClusterConfig.Tls,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create authentication: %w", err)
@@ -2,14 +2,43 @@ package node
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/zncdatadev/operator-go/pkg/builder"
 	"github.com/zncdatadev/operator-go/pkg/client"
+	"github.com/zncdatadev/operator-go/pkg/constants"
 	"github.com/zncdatadev/operator-go/pkg/reconciler"
 	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
 )
 
+var serviceLogger = ctrl.Log.WithName("node").WithName("service")
+
+// UIServiceName returns the name of the Service exposing the NiFi UI and REST API of all nodes.
+func UIServiceName(clusterName, roleName string) string {
+	return fmt.Sprintf("%s-%s", clusterName, roleName)
+}
+
+// getListenerClass returns the listener class of the UI Service, cluster-internal by default.
+func getListenerClass(clusterConfig *nifiv1alpha1.ClusterConfigSpec) constants.ListenerClass {
+	if clusterConfig == nil || clusterConfig.ListenerClass == "" {
+		return constants.ClusterInternal
+	}
+	return constants.ListenerClass(clusterConfig.ListenerClass)
+}
+
+// getWebPort returns the port serving the UI and REST API, https when TLS is enabled.
+func getWebPort(clusterConfig *nifiv1alpha1.ClusterConfigSpec) corev1.ContainerPort {
+	name := "http"
+	if clusterConfig != nil && clusterConfig.Tls != nil {
+		name = "https"
+	}
+	return corev1.ContainerPort{Name: name, ContainerPort: getPort(name)}
+}
+
 type ServiceBuilder struct {
 	*builder.BaseServiceBuilder
 }
@@ -59,3 +88,82 @@ func NewServiceReconciler(
 		),
 	}
 }
+
+// HeadlessServiceReconciler recreates the role group Service when it still has a cluster IP, the API server
+// refuses to update `spec.clusterIP`. Services created before the node Services became headless are migrated
+// this way on upgrade.
+type HeadlessServiceReconciler struct {
+	*reconciler.Service
+}
+
+// NewHeadlessServiceReconciler returns the reconciler of the headless Service resolving the pod DNS names
+// of a role group.
+func NewHeadlessServiceReconciler(
+	client *client.Client,
+	name string,
+	ports []corev1.ContainerPort,
+	options ...builder.ServiceBuilderOption,
+) *HeadlessServiceReconciler {
+	return &HeadlessServiceReconciler{
+		Service: NewServiceReconciler(
+			client,
+			name,
+			ports,
+			append(options, func(o *builder.ServiceBuilderOptions) {
+				// Only headless with the cluster-internal listener class, see builder.BaseServiceBuilder.
+				o.ListenerClass = constants.ClusterInternal
+				o.Headless = true
+			})...,
+		),
+	}
+}
+
+func (r *HeadlessServiceReconciler) Reconcile(ctx context.Context) (ctrl.Result, error) {
+	obj, err := r.GetBuilder().Build(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	existing := &corev1.Service{}
+	if err := r.Client.Get(ctx, ctrlclient.ObjectKeyFromObject(obj), existing); err != nil {
+		if ctrlclient.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+	} else if existing.Spec.ClusterIP != "" && existing.Spec.ClusterIP != corev1.ClusterIPNone {
+		serviceLogger.Info("Recreating Service, it is not headless", "namespace", existing.Namespace, "name", existing.Name)
+		if err := r.Client.Client.Delete(ctx, existing); ctrlclient.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		// The Service is created again once the deletion is done.
+		return ctrl.Result{RequeueAfter: r.RequeueAfter}, nil
+	}
+
+	return r.ResourceReconcile(ctx, obj)
+}
+
+// NewUIServiceReconciler returns the reconciler of the Service exposing the web port of all nodes
+// of the role. Its type follows `clusterConfig.listenerClass`:
+//
+//	cluster-internal --> ClusterIP
+//	external-unstable --> NodePort
+//	external-stable --> LoadBalancer
+//
+// Node-to-node traffic keeps using the internal headless Services of the role groups.
+func NewUIServiceReconciler(
+	client *client.Client,
+	roleInfo reconciler.RoleInfo,
+	clusterConfig *nifiv1alpha1.ClusterConfigSpec,
+) *reconciler.Service {
+	return NewServiceReconciler(
+		client,
+		UIServiceName(roleInfo.GetClusterName(), roleInfo.GetRoleName()),
+		[]corev1.ContainerPort{getWebPort(clusterConfig)},
+		func(o *builder.ServiceBuilderOptions) {
+			o.ListenerClass = getListenerClass(clusterConfig)
+			o.ClusterName = roleInfo.GetClusterName()
+			o.RoleName = roleInfo.GetRoleName()
+			o.Labels = roleInfo.GetLabels()
+			o.Annotations = roleInfo.GetAnnotations()
+		},
+	)
+}
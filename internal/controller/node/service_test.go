@@ -0,0 +1,153 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zncdatadev/operator-go/pkg/builder"
+	"github.com/zncdatadev/operator-go/pkg/client"
+	"github.com/zncdatadev/operator-go/pkg/constants"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func TestGetListenerClass(t *testing.T) {
+	tests := []struct {
+		listenerClass string
+		expected      corev1.ServiceType
+	}{
+		{listenerClass: "", expected: corev1.ServiceTypeClusterIP},
+		{listenerClass: string(constants.ClusterInternal), expected: corev1.ServiceTypeClusterIP},
+		{listenerClass: string(constants.ExternalUnstable), expected: corev1.ServiceTypeNodePort},
+		{listenerClass: string(constants.ExternalStable), expected: corev1.ServiceTypeLoadBalancer},
+	}
+
+	for _, tt := range tests {
+		clusterConfig := &nifiv1alpha1.ClusterConfigSpec{ListenerClass: tt.listenerClass}
+		if got := builder.ListenerClass2ServiceType(getListenerClass(clusterConfig)); got != tt.expected {
+			t.Errorf("listener class %q: expected service type %s, got %s", tt.listenerClass, tt.expected, got)
+		}
+	}
+}
+
+func TestGetWebPort(t *testing.T) {
+	if port := getWebPort(&nifiv1alpha1.ClusterConfigSpec{}); port.Name != "http" || port.ContainerPort != 8088 {
+		t.Errorf("expected the http port without TLS, got %s:%d", port.Name, port.ContainerPort)
+	}
+
+	tls := &nifiv1alpha1.ClusterConfigSpec{Tls: &nifiv1alpha1.TlsSpec{}}
+	if port := getWebPort(tls); port.Name != "https" || port.ContainerPort != 9443 {
+		t.Errorf("expected the https port with TLS, got %s:%d", port.Name, port.ContainerPort)
+	}
+}
+
+func newTestClient() *client.Client {
+	return client.NewClient(nil, &nifiv1alpha1.NifiCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi", Namespace: "default"},
+	})
+}
+
+func TestUIService(t *testing.T) {
+	roleInfo := reconciler.RoleInfo{
+		ClusterInfo: reconciler.ClusterInfo{
+			GVK:         &metav1.GroupVersionKind{Group: "nifi.kubedoop.dev", Version: "v1alpha1", Kind: "NifiCluster"},
+			ClusterName: "simple-nifi",
+		},
+		RoleName: "node",
+	}
+	clusterConfig := &nifiv1alpha1.ClusterConfigSpec{
+		ListenerClass: string(constants.ExternalStable),
+		Tls:           &nifiv1alpha1.TlsSpec{},
+	}
+
+	obj, err := NewUIServiceReconciler(newTestClient(), roleInfo, clusterConfig).GetBuilder().Build(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svc := obj.(*corev1.Service)
+
+	if svc.Name != "simple-nifi-node" {
+		t.Errorf("expected service simple-nifi-node, got %s", svc.Name)
+	}
+	if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		t.Errorf("expected a LoadBalancer service, got %s", svc.Spec.Type)
+	}
+	if len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Name != "https" {
+		t.Errorf("expected only the https port, got %v", svc.Spec.Ports)
+	}
+	// All role groups are selected.
+	if _, ok := svc.Spec.Selector[constants.LabelKubernetesRoleGroup]; ok {
+		t.Errorf("expected no role group in the selector, got %v", svc.Spec.Selector)
+	}
+	if svc.Spec.Selector[constants.LabelKubernetesComponent] != "node" {
+		t.Errorf("expected the node role in the selector, got %v", svc.Spec.Selector)
+	}
+}
+
+func TestServiceBuilder_Headless(t *testing.T) {
+	labels := map[string]string{constants.LabelKubernetesInstance: "simple-nifi"}
+
+	for _, listenerClass := range []constants.ListenerClass{constants.ClusterInternal, constants.ExternalUnstable} {
+		svc := NewServiceBuilder(newTestClient(), "simple-nifi-node-default", Ports, func(o *builder.ServiceBuilderOptions) {
+			o.ListenerClass = listenerClass
+			o.Headless = true
+			o.Labels = labels
+		}).GetObject()
+
+		headless := svc.Spec.ClusterIP == corev1.ClusterIPNone
+		if expected := listenerClass == constants.ClusterInternal; headless != expected {
+			t.Errorf("listener class %s: expected headless %t, got %t", listenerClass, expected, headless)
+		}
+	}
+}
+
+func TestHeadlessServiceReconciler_Recreate(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := nifiv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	// A role group Service created before it became headless.
+	existing := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi-node-default", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.10"},
+	}
+	c := client.NewClient(
+		fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build(),
+		&nifiv1alpha1.NifiCluster{ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi", Namespace: "default", UID: "uid"}},
+	)
+	r := NewHeadlessServiceReconciler(c, "simple-nifi-node-default", Ports)
+
+	result, err := r.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Error("expected a requeue once the Service is deleted")
+	}
+	svc := &corev1.Service{}
+	if err := c.GetCtrlClient().Get(ctx, ctrlclient.ObjectKeyFromObject(existing), svc); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the Service with a cluster IP deleted, got %v", err)
+	}
+
+	if _, err := r.Reconcile(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.GetCtrlClient().Get(ctx, ctrlclient.ObjectKeyFromObject(existing), svc); err != nil {
+		t.Fatalf("expected the Service created again: %v", err)
+	}
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		t.Errorf("expected a headless Service, got cluster IP %q", svc.Spec.ClusterIP)
+	}
+}
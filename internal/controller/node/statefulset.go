@@ -12,7 +12,10 @@ import (
 	"github.com/zncdatadev/operator-go/pkg/util"
 	appv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
@@ -25,6 +28,7 @@ const (
 	NifiConfigVolumeName        = "nifi-config"
 	NifiAdminPasswordVolumeName = "nifi-admin-password"
 	EmptyDirVolumeName          = "empty-dir"
+	NifiServerTlsVolumeName     = "server-tls"
 )
 
 // NifiServiceAccountName returns the ServiceAccount name for NiFi pods.
@@ -33,6 +37,8 @@ func NifiServiceAccountName(clusterName string) string {
 	return clusterName + "-nifi"
 }
 
+var statefulSetLogger = ctrl.Log.WithName("node").WithName("statefulset")
+
 var _ builder.StatefulSetBuilder = &StatefulSetBuilder{}
 
 type StatefulSetBuilder struct {
@@ -43,6 +49,11 @@ type StatefulSetBuilder struct {
 	RoleName         string
 	Authentication   *security.Authentication
 	GitSyncResources *common.GitSyncResources
+	RoleGroupConfig  *nifiv1alpha1.ConfigSpec
+	// StorePassword encrypts the PKCS12 stores issued by the secret-operator, it is read in Build.
+	StorePassword string
+	// ExtraVolumes are the decoded `clusterConfig.extraVolumes`.
+	ExtraVolumes []corev1.Volume
 }
 
 func NewStatefulSetReconciler(
@@ -56,7 +67,7 @@ func NewStatefulSetReconciler(
 	authentication *security.Authentication,
 	overrides *commonsv1alpha1.OverridesSpec,
 	roleGroupConfig *nifiv1alpha1.ConfigSpec,
-) (*reconciler.StatefulSet, error) {
+) (*StatefulSetReconciler, error) {
 
 	var commonsRoleGroupConfig *commonsv1alpha1.RoleGroupConfigSpec
 	if roleGroupConfig != nil {
@@ -68,6 +79,11 @@ func NewStatefulSetReconciler(
 		return nil, fmt.Errorf("building git-sync resources: %w", err)
 	}
 
+	extraVolumes, err := common.DecodeExtraVolumes(clusterConfig.ExtraVolumes)
+	if err != nil {
+		return nil, err
+	}
+
 	stsBuilder := &StatefulSetBuilder{
 		StatefulSet: *builder.NewStatefulSetBuilder(
 			client,
@@ -90,13 +106,169 @@ func NewStatefulSetReconciler(
 		RoleName:         roleGroupInfo.GetRoleName(),
 		Authentication:   authentication,
 		GitSyncResources: gitSyncResources,
+		RoleGroupConfig:  roleGroupConfig,
+		ExtraVolumes:     extraVolumes,
+	}
+
+	return &StatefulSetReconciler{
+		StatefulSet: reconciler.NewStatefulSet(
+			client,
+			stsBuilder,
+			stopped,
+		),
+	}, nil
+}
+
+// StatefulSetReconciler recreates the StatefulSet when its volumeClaimTemplates change, e.g. when a
+// repository volume is added, the API server refuses to update them. A changed capacity only expands the
+// existing PersistentVolumeClaims, see expandVolumeClaims.
+//
+// It also recreates the StatefulSet when the store password changes, the operator-go client logs the patch
+// of an update at debug level, which would then contain the password, see storePasswordChanged.
+type StatefulSetReconciler struct {
+	*reconciler.StatefulSet
+}
+
+func (r *StatefulSetReconciler) Reconcile(ctx context.Context) (ctrl.Result, error) {
+	resourceBuilder := r.GetBuilder()
+	if r.Stopped {
+		resourceBuilder.SetReplicas(ptr.To[int32](0))
+	}
+
+	obj, err := resourceBuilder.Build(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	desired, ok := obj.(*appv1.StatefulSet)
+	if !ok {
+		return ctrl.Result{}, fmt.Errorf("expected *appv1.StatefulSet from StatefulSet builder, got %T", obj)
+	}
+
+	existing := &appv1.StatefulSet{}
+	if err := r.Client.Get(ctx, ctrlclient.ObjectKeyFromObject(desired), existing); err != nil {
+		if ctrlclient.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+	} else if reason := getRecreateReason(existing, desired); reason != "" {
+		// The pods and their PersistentVolumeClaims are orphaned and adopted by the new StatefulSet,
+		// which rolls them one at a time to mount the new volumes. Only the name is logged, the volumes
+		// hold the store password.
+		statefulSetLogger.Info("Recreating StatefulSet, its "+reason+" changed", "namespace", existing.Namespace, "name", existing.Name)
+		if err := r.Client.Client.Delete(ctx, existing, ctrlclient.PropagationPolicy(metav1.DeletePropagationOrphan)); ctrlclient.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		// The StatefulSet is created again once the deletion is done.
+		return ctrl.Result{RequeueAfter: r.RequeueAfter}, nil
+	} else {
+		if err := expandVolumeClaims(ctx, r.Client, existing, desired.Spec.VolumeClaimTemplates); err != nil {
+			return ctrl.Result{}, err
+		}
+		// The API server refuses to update the volumeClaimTemplates, the new capacity only applies to the
+		// PersistentVolumeClaims expanded above.
+		desired.Spec.VolumeClaimTemplates = existing.Spec.VolumeClaimTemplates
+	}
+
+	return r.ResourceReconcile(ctx, desired)
+}
+
+// getRecreateReason returns what changed when the StatefulSet must be recreated, empty otherwise.
+func getRecreateReason(existing, desired *appv1.StatefulSet) string {
+	if volumeClaimTemplatesChanged(existing.Spec.VolumeClaimTemplates, desired.Spec.VolumeClaimTemplates) {
+		return "volumeClaimTemplates"
+	}
+	if storePasswordChanged(existing.Spec.Template.Spec.Volumes, desired.Spec.Template.Spec.Volumes) {
+		return "store password"
 	}
+	return ""
+}
 
-	return reconciler.NewStatefulSet(
-		client,
-		stsBuilder,
-		stopped,
-	), nil
+// storePasswordChanged reports whether the PKCS12 password of a secret-operator volume changed, e.g. after the
+// store password secret was deleted and generated again.
+func storePasswordChanged(existing, desired []corev1.Volume) bool {
+	passwords := make(map[string]string, len(existing))
+	for _, volume := range existing {
+		if password, ok := getPKCS12Password(volume); ok {
+			passwords[volume.Name] = password
+		}
+	}
+	for _, volume := range desired {
+		password, ok := getPKCS12Password(volume)
+		if !ok {
+			continue
+		}
+		if existingPassword, exists := passwords[volume.Name]; exists && existingPassword != password {
+			return true
+		}
+	}
+	return false
+}
+
+// getPKCS12Password returns the PKCS12 password annotation of a secret-operator volume.
+func getPKCS12Password(volume corev1.Volume) (string, bool) {
+	if volume.Ephemeral == nil || volume.Ephemeral.VolumeClaimTemplate == nil {
+		return "", false
+	}
+	password, ok := volume.Ephemeral.VolumeClaimTemplate.Annotations[constants.AnnotationSecretsPKCS12Password]
+	return password, ok
+}
+
+// volumeClaimTemplatesChanged reports whether the StatefulSet must be recreated to apply the volumeClaimTemplates.
+// Only the fields set by getRepositoryVolumeClaimTemplates are compared, the API server defaults the others.
+// The capacity is not compared, the PersistentVolumeClaims are expanded instead.
+func volumeClaimTemplatesChanged(existing, desired []corev1.PersistentVolumeClaim) bool {
+	if len(existing) != len(desired) {
+		return true
+	}
+	for i := range desired {
+		if existing[i].Name != desired[i].Name ||
+			ptr.Deref(existing[i].Spec.StorageClassName, "") != ptr.Deref(desired[i].Spec.StorageClassName, "") {
+			return true
+		}
+	}
+	return false
+}
+
+// expandVolumeClaims requests the desired capacity on the PersistentVolumeClaims of the StatefulSet pods,
+// the StorageClass must allow volume expansion. A claim is never shrunk, the API server refuses it.
+func expandVolumeClaims(
+	ctx context.Context,
+	client *client.Client,
+	sts *appv1.StatefulSet,
+	templates []corev1.PersistentVolumeClaim,
+) error {
+	for _, template := range templates {
+		capacity := template.Spec.Resources.Requests.Storage()
+		for i := int32(0); i < ptr.Deref(sts.Spec.Replicas, 1); i++ {
+			pvc := &corev1.PersistentVolumeClaim{}
+			key := ctrlclient.ObjectKey{Namespace: sts.Namespace, Name: fmt.Sprintf("%s-%s-%d", template.Name, sts.Name, i)}
+			if err := client.Get(ctx, key, pvc); err != nil {
+				if ctrlclient.IgnoreNotFound(err) != nil {
+					return err
+				}
+				continue
+			}
+
+			switch current := pvc.Spec.Resources.Requests.Storage(); current.Cmp(*capacity) {
+			case 0:
+				continue
+			case 1:
+				statefulSetLogger.Info("Not shrinking PersistentVolumeClaim", "namespace", pvc.Namespace, "name", pvc.Name,
+					"capacity", current.String(), "desired", capacity.String())
+				continue
+			}
+
+			statefulSetLogger.Info("Expanding PersistentVolumeClaim", "namespace", pvc.Namespace, "name", pvc.Name, "capacity", capacity.String())
+			patch := ctrlclient.MergeFrom(pvc.DeepCopy())
+			if pvc.Spec.Resources.Requests == nil {
+				pvc.Spec.Resources.Requests = corev1.ResourceList{}
+			}
+			pvc.Spec.Resources.Requests[corev1.ResourceStorage] = *capacity
+			if err := client.Client.Patch(ctx, pvc, patch); err != nil {
+				return fmt.Errorf("failed to expand PersistentVolumeClaim %s/%s: %w", pvc.Namespace, pvc.Name, err)
+			}
+		}
+	}
+	return nil
 }
 
 func (b *StatefulSetBuilder) Build(ctx context.Context) (ctrlclient.Object, error) {
@@ -109,9 +281,15 @@ func (b *StatefulSetBuilder) Build(ctx context.Context) (ctrlclient.Object, erro
 		b.AddInitContainer(&b.GitSyncResources.GitSyncInitContainers[i])
 	}
 
+	repositoryVolumeMounts, err := getRepositoryVolumeMounts(b.getStorage())
+	if err != nil {
+		return nil, err
+	}
+
 	mainContainerBuilder := b.getMainContainerBuilder()
 	// Expose the synced git content to the main NiFi container.
 	mainContainerBuilder.AddVolumeMounts(b.GitSyncResources.GitSyncVolumeMounts)
+	mainContainerBuilder.AddVolumeMounts(repositoryVolumeMounts)
 	mainContainer := mainContainerBuilder.Build()
 	b.AddContainer(mainContainer)
 
@@ -120,9 +298,34 @@ func (b *StatefulSetBuilder) Build(ctx context.Context) (ctrlclient.Object, erro
 		b.AddContainer(&b.GitSyncResources.GitSyncContainers[i])
 	}
 
+	storePassword, err := security.GetStorePassword(ctx, b.Client, b.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+	b.StorePassword = storePassword
+
 	volumes := b.getVolumes()
+
+	// The Vector agent ships the logs NiFi writes to the shared log volume.
+	if IsVectorEnabled(b.RoleGroupConfig) {
+		vector := builder.NewVector(NifiConfigVolumeName, builder.LogDataVolumeName, b.Image)
+		b.AddContainer(vector.GetContainer())
+		volumes = append(volumes, vector.GetVolumes()...)
+	}
+
 	b.AddVolumes(volumes)
 
+	// Persist the NiFi repositories across pod restarts.
+	pvcs, err := getRepositoryVolumeClaimTemplates(b.getStorage())
+	if err != nil {
+		return nil, err
+	}
+	b.AddVolumeClaimTemplates(pvcs)
+
+	if err := checkVolumeNames(volumes, pvcs); err != nil {
+		return nil, err
+	}
+
 	obj, err := b.StatefulSet.Build(ctx)
 	if err != nil {
 		return nil, err
@@ -136,6 +339,16 @@ func (b *StatefulSetBuilder) Build(ctx context.Context) (ctrlclient.Object, erro
 	}
 	sts.Spec.Template.Spec.ServiceAccountName = NifiServiceAccountName(b.ClusterName)
 
+	// logback.xml is copied into the NiFi config dir at startup, restart the pods when it changes.
+	loggingConfigHash, err := getLoggingConfigHash(b.RoleGroupConfig, b.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	if sts.Spec.Template.Annotations == nil {
+		sts.Spec.Template.Annotations = map[string]string{}
+	}
+	sts.Spec.Template.Annotations[LoggingConfigHashAnnotation] = loggingConfigHash
+
 	return sts, nil
 }
 
@@ -177,10 +390,20 @@ export NODE_ADDRESS="` + nodeAddress + `"
 ` + authArgs + `
 
 gomplate -f ` + constants.KubedoopConfigDirMount + `/nifi.properties -o ` + NifiConfigDir + `/nifi.properties
-gomplate -f ` + constants.KubedoopConfigDirMount + `/login-identity-providers.xml -o ` + NifiConfigDir + `/login-identity-providers.xml
 gomplate -f ` + constants.KubedoopConfigDirMount + `/state-management.xml -o ` + NifiConfigDir + `/state-management.xml
 `
 
+	// login-identity-providers.xml and authorizers.xml are only generated with authentication.
+	if b.Authentication != nil {
+		files := []string{AuthorizersFile}
+		if b.Authentication.HasLoginIdentityProvider() {
+			files = append([]string{LoginIdentityProvidersFile}, files...)
+		}
+		for _, file := range files {
+			args += `gomplate -f ` + path.Join(constants.KubedoopConfigDirMount, file) + ` -o ` + path.Join(NifiConfigDir, file) + "\n"
+		}
+	}
+
 	return util.IndentTab4Spaces(args)
 }
 
@@ -196,6 +419,13 @@ func (b *StatefulSetBuilder) getMainContainerBuilder() builder.ContainerBuilder
 	return container
 }
 
+func (b *StatefulSetBuilder) getStorage() *nifiv1alpha1.StorageSpec {
+	if b.RoleGroupConfig == nil {
+		return nil
+	}
+	return b.RoleGroupConfig.Storage
+}
+
 func (b *StatefulSetBuilder) setupMainContainerProbe(container builder.ContainerBuilder) {
 	port := intstr.FromString("http")
 
@@ -234,7 +464,7 @@ func (b *StatefulSetBuilder) getMainContainerArgs() string {
 	args := util.CommonBashTrapFunctions + `
 
 ` + util.RemoveVectorShutdownFileCommand() + `
-
+` + b.getSeedAuthorizationsArgs() + `
 prepare_signal_handlers
 
 # sleep infinity
@@ -249,6 +479,22 @@ wait_for_termination $!
 	return util.IndentTab4Spaces(args)
 }
 
+// getSeedAuthorizationsArgs copies the users and authorizations seeding the OIDC group policies to the database
+// repository. Existing files are kept, the users and policies managed in NiFi survive restarts.
+func (b *StatefulSetBuilder) getSeedAuthorizationsArgs() string {
+	opa := b.ClusterConfig.Authorization != nil && b.ClusterConfig.Authorization.Opa != nil
+	if b.Authentication == nil || opa || !b.Authentication.HasGroupPolicies() {
+		return ""
+	}
+
+	dataDir := NifiRepositoryMouhtPath["database"]
+	args := "\nmkdir -p " + dataDir + "\n"
+	for _, seed := range [][2]string{{InitialUsersFile, "users.xml"}, {InitialAuthorizationsFile, "authorizations.xml"}} {
+		args += fmt.Sprintf("[ -f %[2]s ] || cp %[1]s %[2]s\n", path.Join(NifiConfigDir, seed[0]), path.Join(dataDir, seed[1]))
+	}
+	return args
+}
+
 func (b *StatefulSetBuilder) getContainerEnv() []corev1.EnvVar {
 	envVars := []corev1.EnvVar{
 		{
@@ -293,10 +539,17 @@ func (b *StatefulSetBuilder) getContainerEnv() []corev1.EnvVar {
 		})
 	}
 
+	// The stores are decrypted with the store password, gomplate renders it into the config files.
+	envVars = append(envVars, security.GetStorePasswordEnvVar(b.ClusterName))
+
 	if b.Authentication != nil {
 		envVars = append(envVars, b.Authentication.GetEnvVars()...)
 	}
 
+	if b.ClusterConfig.Authorization != nil && b.ClusterConfig.Authorization.Opa != nil {
+		envVars = append(envVars, security.GetOpaEnvVars(b.ClusterConfig.Authorization.Opa)...)
+	}
+
 	return envVars
 }
 
@@ -315,14 +568,27 @@ func (b *StatefulSetBuilder) getVolumeMounts() []corev1.VolumeMount {
 		},
 	}
 
+	if b.ClusterConfig.Tls != nil {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      NifiServerTlsVolumeName,
+			MountPath: NifiServerTlsDir,
+			ReadOnly:  true,
+		})
+	}
+
 	if b.Authentication != nil {
 		volumeMounts = append(volumeMounts, b.Authentication.GetVolumeMounts()...)
 	}
 
+	volumeMounts = append(volumeMounts, getLogVolumeMounts()...)
+
+	volumeMounts = append(volumeMounts, common.GetExtraVolumeMounts(b.ExtraVolumes)...)
+
 	return volumeMounts
 }
 
 func (b *StatefulSetBuilder) getVolumes() []corev1.Volume {
+	logVolumeSizeLimit := getLogVolumeSizeLimit()
 	volumes := []corev1.Volume{
 		{
 			Name: NifiConfigVolumeName,
@@ -340,6 +606,17 @@ func (b *StatefulSetBuilder) getVolumes() []corev1.Volume {
 				EmptyDir: &corev1.EmptyDirVolumeSource{},
 			},
 		},
+		{
+			Name: builder.LogDataVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					SizeLimit: &logVolumeSizeLimit,
+				},
+			},
+		},
+	}
+	if b.ClusterConfig.Tls != nil {
+		volumes = append(volumes, b.getServerTlsVolume())
 	}
 	if b.Authentication != nil {
 		volumes = append(volumes, b.Authentication.GetVolumes()...)
@@ -348,5 +625,42 @@ func (b *StatefulSetBuilder) getVolumes() []corev1.Volume {
 	// Add the EmptyDir volumes backing each git-sync instance.
 	volumes = append(volumes, b.GitSyncResources.GitSyncVolumes...)
 
+	volumes = append(volumes, b.ExtraVolumes...)
+
 	return volumes
 }
+
+// getServerTlsVolume returns the keystore and truststore of the NiFi server, issued by the secret-operator.
+// The pod scope adds the address of the pod in the headless Service, NODE_ADDRESS, to the SANs of the certificate.
+// The secret-operator only reads the PKCS12 password from the volume annotation, see security.StorePasswordSecretName.
+func (b *StatefulSetBuilder) getServerTlsVolume() corev1.Volume {
+	tls := builder.NewSecretOperatorVolume(NifiServerTlsVolumeName, b.ClusterConfig.Tls.ServerSecretClass)
+	tls.SetFormatName(constants.TLSP12)
+	tls.SetScope(&builder.SecretVolumeScope{
+		Pod:     true,
+		Node:    true,
+		Service: []string{b.Name},
+	})
+	tls.SetPKCS12Password(b.StorePassword)
+	if b.ClusterConfig.Tls.CertLifetime != "" {
+		tls.SetCertLifeTime(b.ClusterConfig.Tls.CertLifetime)
+	}
+	return *tls.Builde()
+}
+
+// checkVolumeNames rejects volumes sharing a name, e.g. an extra volume named like a repository volume.
+func checkVolumeNames(volumes []corev1.Volume, pvcs []corev1.PersistentVolumeClaim) error {
+	names := make(map[string]struct{}, len(volumes)+len(pvcs))
+	for _, volume := range volumes {
+		if _, exists := names[volume.Name]; exists {
+			return fmt.Errorf("volume name %q is used more than once, rename the extra volume", volume.Name)
+		}
+		names[volume.Name] = struct{}{}
+	}
+	for _, pvc := range pvcs {
+		if _, exists := names[pvc.Name]; exists {
+			return fmt.Errorf("volume name %q is reserved for the %s PersistentVolumeClaim, rename the extra volume", pvc.Name, pvc.Name)
+		}
+	}
+	return nil
+}
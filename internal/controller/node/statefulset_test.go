@@ -0,0 +1,316 @@
+package node
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/zncdatadev/operator-go/pkg/builder"
+	"github.com/zncdatadev/operator-go/pkg/client"
+	"github.com/zncdatadev/operator-go/pkg/constants"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+	"github.com/zncdatadev/nifi-operator/internal/common"
+	"github.com/zncdatadev/nifi-operator/internal/common/security"
+)
+
+func newTlsStatefulSetBuilder() *StatefulSetBuilder {
+	return &StatefulSetBuilder{
+		StatefulSet:      *builder.NewStatefulSetBuilder(nil, "simple-nifi-node-default", nil, nil, nil, nil),
+		ClusterConfig:    &nifiv1alpha1.ClusterConfigSpec{Tls: &nifiv1alpha1.TlsSpec{ServerSecretClass: "tls"}},
+		GitSyncResources: &common.GitSyncResources{},
+		StorePassword:    "secret",
+	}
+}
+
+func TestStatefulSetBuilder_ServerTlsVolume(t *testing.T) {
+	b := newTlsStatefulSetBuilder()
+
+	var found bool
+	for _, volume := range b.getVolumes() {
+		if volume.Name != NifiServerTlsVolumeName {
+			continue
+		}
+		found = true
+		annotations := volume.Ephemeral.VolumeClaimTemplate.Annotations
+		for key, expected := range map[string]string{
+			constants.AnnotationSecretsClass:          "tls",
+			constants.AnnotationSecretsFormat:         string(constants.TLSP12),
+			constants.AnnotationSecretsScope:          "pod,node,service=simple-nifi-node-default",
+			constants.AnnotationSecretsPKCS12Password: "secret",
+		} {
+			if annotations[key] != expected {
+				t.Errorf("expected annotation %s=%s, got %q", key, expected, annotations[key])
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected the %s volume", NifiServerTlsVolumeName)
+	}
+
+	var mounted bool
+	for _, mount := range b.getVolumeMounts() {
+		if mount.Name == NifiServerTlsVolumeName {
+			mounted = mount.MountPath == NifiServerTlsDir
+		}
+	}
+	if !mounted {
+		t.Errorf("expected the %s volume mounted in %s", NifiServerTlsVolumeName, NifiServerTlsDir)
+	}
+
+	b.ClusterConfig.Tls = nil
+	for _, volume := range b.getVolumes() {
+		if volume.Name == NifiServerTlsVolumeName {
+			t.Error("expected no server TLS volume without TLS")
+		}
+	}
+}
+
+func TestStatefulSetBuilder_StorePasswordEnv(t *testing.T) {
+	b := newTlsStatefulSetBuilder()
+	b.ClusterName = "simple-nifi"
+
+	for _, env := range b.getContainerEnv() {
+		if env.Name != security.StorePasswordEnvName {
+			continue
+		}
+		ref := env.ValueFrom.SecretKeyRef
+		if ref == nil || ref.Name != "simple-nifi-tls-store-password" || ref.Key != security.StorePasswordKey {
+			t.Errorf("expected the store password from the store password secret, got %+v", env.ValueFrom)
+		}
+		return
+	}
+	t.Errorf("expected the %s env var", security.StorePasswordEnvName)
+}
+
+func TestStatefulSetBuilder_ServerTlsCertLifetime(t *testing.T) {
+	b := newTlsStatefulSetBuilder()
+
+	if lifetime, ok := b.getServerTlsVolume().Ephemeral.VolumeClaimTemplate.Annotations[constants.AnnotationSecretCertLifeTime]; ok {
+		t.Errorf("expected the lifetime of the SecretClass by default, got %q", lifetime)
+	}
+
+	b.ClusterConfig.Tls.CertLifetime = "24h"
+	if lifetime := b.getServerTlsVolume().Ephemeral.VolumeClaimTemplate.Annotations[constants.AnnotationSecretCertLifeTime]; lifetime != "24h" {
+		t.Errorf("expected the requested lifetime 24h, got %q", lifetime)
+	}
+}
+
+func TestVolumeClaimTemplatesChanged(t *testing.T) {
+	storage := &nifiv1alpha1.StorageSpec{}
+	existing, err := getRepositoryVolumeClaimTemplates(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if volumeClaimTemplatesChanged(existing, existing) {
+		t.Error("expected unchanged volumeClaimTemplates")
+	}
+	if !volumeClaimTemplatesChanged(nil, existing) {
+		t.Error("expected a change when the StatefulSet has no volumeClaimTemplates yet")
+	}
+
+	storage.ContentRepoVolumes = map[string]nifiv1alpha1.RepositoryStorageSpec{"disk1": {}}
+	added, err := getRepositoryVolumeClaimTemplates(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !volumeClaimTemplatesChanged(existing, added) {
+		t.Error("expected a change when a repository volume is added")
+	}
+
+	capacity := resource.MustParse("10Gi")
+	storage.ContentRepoVolumes = nil
+	storage.ContentRepo = &nifiv1alpha1.RepositoryStorageSpec{Capacity: &capacity, StorageClass: "fast"}
+	resized, err := getRepositoryVolumeClaimTemplates(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !volumeClaimTemplatesChanged(existing, resized) {
+		t.Error("expected a change when the storage class of a repository changes")
+	}
+
+	storage.ContentRepo.StorageClass = ""
+	expanded, err := getRepositoryVolumeClaimTemplates(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volumeClaimTemplatesChanged(existing, expanded) {
+		t.Error("expected no recreation when only the capacity of a repository changes")
+	}
+}
+
+func TestExpandVolumeClaims(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := nifiv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	newPVC := func(name, capacity string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PersistentVolumeClaimSpec{Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(capacity)},
+			}},
+		}
+	}
+	c := client.NewClient(
+		fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			newPVC("content-repository-simple-nifi-node-default-0", "4Gi"),
+			newPVC("content-repository-simple-nifi-node-default-1", "20Gi"),
+		).Build(),
+		&nifiv1alpha1.NifiCluster{ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi", Namespace: "default", UID: "uid"}},
+	)
+	sts := &appv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi-node-default", Namespace: "default"},
+		// The PVC of the third pod is not created yet.
+		Spec: appv1.StatefulSetSpec{Replicas: ptr.To[int32](3)},
+	}
+	templates := []corev1.PersistentVolumeClaim{newRepositoryPVC("content-repository", resource.MustParse("10Gi"), "")}
+
+	if err := expandVolumeClaims(ctx, c, sts, templates); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		"content-repository-simple-nifi-node-default-0": "10Gi",
+		// A claim is never shrunk.
+		"content-repository-simple-nifi-node-default-1": "20Gi",
+	} {
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := c.GetCtrlClient().Get(ctx, ctrlclient.ObjectKey{Namespace: "default", Name: name}, pvc); err != nil {
+			t.Fatal(err)
+		}
+		if got := pvc.Spec.Resources.Requests.Storage(); got.Cmp(resource.MustParse(expected)) != 0 {
+			t.Errorf("%s: expected capacity %s, got %s", name, expected, got)
+		}
+	}
+}
+
+func TestStatefulSetBuilder_OpaEnv(t *testing.T) {
+	b := newTlsStatefulSetBuilder()
+	b.ClusterConfig.Authorization = &nifiv1alpha1.AuthorizationSpec{Opa: &nifiv1alpha1.OpaSpec{ConfigMapName: "opa"}}
+
+	for _, env := range b.getContainerEnv() {
+		if env.Name != security.OpaBaseURLEnvName {
+			continue
+		}
+		ref := env.ValueFrom.ConfigMapKeyRef
+		if ref == nil || ref.Name != "opa" || ref.Key != "OPA" {
+			t.Errorf("expected the OPA base URL from the discovery ConfigMap, got %+v", env.ValueFrom)
+		}
+		return
+	}
+	t.Errorf("expected the %s env var", security.OpaBaseURLEnvName)
+}
+
+// fixedStatefulSetBuilder builds the given StatefulSet, standing in for StatefulSetBuilder.
+type fixedStatefulSetBuilder struct {
+	*builder.StatefulSet
+	sts *appv1.StatefulSet
+}
+
+func (b *fixedStatefulSetBuilder) Build(_ context.Context) (ctrlclient.Object, error) {
+	return b.sts.DeepCopy(), nil
+}
+
+func newServerTlsStatefulSet(storePassword string) *appv1.StatefulSet {
+	b := newTlsStatefulSetBuilder()
+	b.StorePassword = storePassword
+	return &appv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi-node-default", Namespace: "default"},
+		Spec: appv1.StatefulSetSpec{
+			Replicas: ptr.To[int32](1),
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Volumes: []corev1.Volume{b.getServerTlsVolume()}}},
+		},
+	}
+}
+
+func TestStorePasswordChanged(t *testing.T) {
+	existing := newServerTlsStatefulSet("old-store-password").Spec.Template.Spec.Volumes
+
+	if storePasswordChanged(existing, newServerTlsStatefulSet("old-store-password").Spec.Template.Spec.Volumes) {
+		t.Error("expected an unchanged store password")
+	}
+	if !storePasswordChanged(existing, newServerTlsStatefulSet("new-store-password").Spec.Template.Spec.Volumes) {
+		t.Error("expected a changed store password")
+	}
+	// Enabling TLS adds the volume, the StatefulSet is updated.
+	if storePasswordChanged(nil, existing) {
+		t.Error("expected no change for a new PKCS12 volume")
+	}
+}
+
+func TestStatefulSetReconciler_StorePasswordNotLogged(t *testing.T) {
+	var mu sync.Mutex
+	var logs strings.Builder
+	ctrl.SetLogger(funcr.New(func(prefix, args string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs.WriteString(prefix + " " + args + "\n")
+	}, funcr.Options{Verbosity: 10}))
+
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := nifiv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := client.NewClient(
+		fake.NewClientBuilder().WithScheme(scheme).WithObjects(newServerTlsStatefulSet("old-store-password")).Build(),
+		&nifiv1alpha1.NifiCluster{ObjectMeta: metav1.ObjectMeta{Name: "simple-nifi", Namespace: "default", UID: "uid"}},
+	)
+	desired := newServerTlsStatefulSet("new-store-password")
+	r := &StatefulSetReconciler{StatefulSet: reconciler.NewStatefulSet(c, &fixedStatefulSetBuilder{
+		StatefulSet: builder.NewStatefulSetBuilder(c, desired.Name, nil, nil, nil, nil),
+		sts:         desired,
+	}, false)}
+
+	// The StatefulSet is recreated instead of patched, the client logs the patch of an update.
+	if _, err := r.Reconcile(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sts := &appv1.StatefulSet{}
+	if err := c.GetCtrlClient().Get(ctx, ctrlclient.ObjectKeyFromObject(desired), sts); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the StatefulSet deleted, got %v", err)
+	}
+	if _, err := r.Reconcile(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.GetCtrlClient().Get(ctx, ctrlclient.ObjectKeyFromObject(desired), sts); err != nil {
+		t.Fatalf("expected the StatefulSet created again: %v", err)
+	}
+	if password, _ := getPKCS12Password(sts.Spec.Template.Spec.Volumes[0]); password != "new-store-password" {
+		t.Errorf("expected the new store password, got %q", password)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(logs.String(), "Recreating StatefulSet") {
+		t.Fatalf("expected the recreation logged, got:\n%s", logs.String())
+	}
+	for _, password := range []string{"old-store-password", "new-store-password"} {
+		if strings.Contains(logs.String(), password) {
+			t.Errorf("expected the store password not to be logged, got:\n%s", logs.String())
+		}
+	}
+}
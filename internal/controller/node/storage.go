@@ -0,0 +1,232 @@
+package node
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/zncdatadev/operator-go/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+// defaultRepositoryDirectoryName is the name of the directory NiFi always configures for the
+// content and provenance repositories, e.g. `nifi.content.repository.directory.default`.
+const defaultRepositoryDirectoryName = "default"
+
+// Repositories are the NiFi repositories backed by a PersistentVolumeClaim,
+// keyed the same way as NifiRepositoryMouhtPath.
+var Repositories = []string{
+	"content",
+	"database",
+	"flowfile",
+	"provenance",
+	"state",
+	"status",
+}
+
+// defaultRepositoryCapacity is used when the repository storage is not set in the spec.
+var defaultRepositoryCapacity = map[string]resource.Quantity{
+	"content":    resource.MustParse("4Gi"),
+	"database":   resource.MustParse("1Gi"),
+	"flowfile":   resource.MustParse("1Gi"),
+	"provenance": resource.MustParse("2Gi"),
+	"state":      resource.MustParse("1Gi"),
+	"status":     resource.MustParse("1Gi"),
+}
+
+// repositoryVolume is a PersistentVolumeClaim backed directory of a NiFi repository.
+type repositoryVolume struct {
+	Name         string
+	MountPath    string
+	Capacity     resource.Quantity
+	StorageClass string
+}
+
+// RepositoryVolumeName returns the volume (and PVC template) name of the repository.
+func RepositoryVolumeName(repository string) string {
+	return nifiRepository(repository)
+}
+
+// repositoryDirectoryVolumeName returns the volume name of an additional, named repository directory.
+func repositoryDirectoryVolumeName(repository, name string) string {
+	return RepositoryVolumeName(repository) + "-" + name
+}
+
+// repositoryDirectoryMountPath returns the mount path of an additional, named repository directory.
+func repositoryDirectoryMountPath(repository, name string) string {
+	return path.Join(constants.KubedoopDataDir, repositoryDirectoryVolumeName(repository, name))
+}
+
+// getRepositoryStorage returns the storage spec of the repository, or nil if it is not set.
+func getRepositoryStorage(storage *nifiv1alpha1.StorageSpec, repository string) *nifiv1alpha1.RepositoryStorageSpec {
+	if storage == nil {
+		return nil
+	}
+
+	switch repository {
+	case "content":
+		return storage.ContentRepo
+	case "database":
+		return storage.DatabaseRepo
+	case "flowfile":
+		return storage.FlowfileRepo
+	case "provenance":
+		return storage.ProvenanceRepo
+	case "state":
+		return storage.StateRepo
+	case "status":
+		return storage.StatusRepo
+	}
+	return nil
+}
+
+// getRepositoryDirectories returns the additional, named directories of the content or provenance repository.
+func getRepositoryDirectories(storage *nifiv1alpha1.StorageSpec, repository string) map[string]nifiv1alpha1.RepositoryStorageSpec {
+	if storage == nil {
+		return nil
+	}
+
+	switch repository {
+	case "content":
+		return storage.ContentRepoVolumes
+	case "provenance":
+		return storage.ProvenanceRepoVolumes
+	}
+	return nil
+}
+
+// getRepositoryCapacity returns the capacity of the repository, falling back to the default.
+func getRepositoryCapacity(storage *nifiv1alpha1.StorageSpec, repository string) resource.Quantity {
+	return capacityOrDefault(getRepositoryStorage(storage, repository), repository)
+}
+
+func capacityOrDefault(s *nifiv1alpha1.RepositoryStorageSpec, repository string) resource.Quantity {
+	if s != nil && s.Capacity != nil && !s.Capacity.IsZero() {
+		return *s.Capacity
+	}
+	return defaultRepositoryCapacity[repository]
+}
+
+// getRepositoryDirectoryPaths returns the mount path of every additional directory of the repository,
+// keyed by directory name.
+func getRepositoryDirectoryPaths(storage *nifiv1alpha1.StorageSpec, repository string) map[string]string {
+	directories := getRepositoryDirectories(storage, repository)
+	paths := make(map[string]string, len(directories))
+	for name := range directories {
+		paths[name] = repositoryDirectoryMountPath(repository, name)
+	}
+	return paths
+}
+
+// ValidateRepositoryDirectoryName checks the name of an additional content or provenance repository directory.
+func ValidateRepositoryDirectoryName(repository, name string) error {
+	if name == defaultRepositoryDirectoryName {
+		return fmt.Errorf("%s repository volume name %q is reserved", repository, name)
+	}
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("invalid %s repository volume name %q: %s", repository, name, strings.Join(errs, ", "))
+	}
+	// The PVC template name ends up in the pod volume name, which must be a DNS-1123 label too.
+	volumeName := repositoryDirectoryVolumeName(repository, name)
+	if errs := validation.IsDNS1123Label(volumeName); len(errs) > 0 {
+		return fmt.Errorf("invalid %s repository volume name %q: %s", repository, name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// getRepositoryVolumes returns every PVC backed repository directory in a deterministic order:
+// the default directory of each repository, followed by the additional content and provenance
+// directories sorted by name.
+func getRepositoryVolumes(storage *nifiv1alpha1.StorageSpec) ([]repositoryVolume, error) {
+	volumes := make([]repositoryVolume, 0, len(Repositories))
+	for _, repository := range Repositories {
+		storageClass := ""
+		if s := getRepositoryStorage(storage, repository); s != nil {
+			storageClass = s.StorageClass
+		}
+		volumes = append(volumes, repositoryVolume{
+			Name:         RepositoryVolumeName(repository),
+			MountPath:    NifiRepositoryMouhtPath[repository],
+			Capacity:     getRepositoryCapacity(storage, repository),
+			StorageClass: storageClass,
+		})
+	}
+
+	for _, repository := range []string{"content", "provenance"} {
+		directories := getRepositoryDirectories(storage, repository)
+		for _, name := range slices.Sorted(maps.Keys(directories)) {
+			if err := ValidateRepositoryDirectoryName(repository, name); err != nil {
+				return nil, err
+			}
+			s := directories[name]
+			volumes = append(volumes, repositoryVolume{
+				Name:         repositoryDirectoryVolumeName(repository, name),
+				MountPath:    repositoryDirectoryMountPath(repository, name),
+				Capacity:     capacityOrDefault(&s, repository),
+				StorageClass: s.StorageClass,
+			})
+		}
+	}
+
+	return volumes, nil
+}
+
+func newRepositoryPVC(name string, capacity resource.Quantity, storageClass string) corev1.PersistentVolumeClaim {
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: capacity,
+				},
+			},
+		},
+	}
+
+	if storageClass != "" {
+		pvc.Spec.StorageClassName = &storageClass
+	}
+
+	return pvc
+}
+
+// getRepositoryVolumeClaimTemplates returns one PVC template per repository directory.
+func getRepositoryVolumeClaimTemplates(storage *nifiv1alpha1.StorageSpec) ([]corev1.PersistentVolumeClaim, error) {
+	volumes, err := getRepositoryVolumes(storage)
+	if err != nil {
+		return nil, err
+	}
+
+	pvcs := make([]corev1.PersistentVolumeClaim, 0, len(volumes))
+	for _, v := range volumes {
+		pvcs = append(pvcs, newRepositoryPVC(v.Name, v.Capacity, v.StorageClass))
+	}
+	return pvcs, nil
+}
+
+// getRepositoryVolumeMounts mounts every repository PVC at its repository directory.
+func getRepositoryVolumeMounts(storage *nifiv1alpha1.StorageSpec) ([]corev1.VolumeMount, error) {
+	volumes, err := getRepositoryVolumes(storage)
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := make([]corev1.VolumeMount, 0, len(volumes))
+	for _, v := range volumes {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      v.Name,
+			MountPath: v.MountPath,
+		})
+	}
+	return mounts, nil
+}
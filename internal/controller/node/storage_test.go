@@ -0,0 +1,157 @@
+package node
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func TestGetRepositoryVolumeClaimTemplates_Defaults(t *testing.T) {
+	pvcs, err := getRepositoryVolumeClaimTemplates(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pvcs) != len(Repositories) {
+		t.Fatalf("expected %d PVC templates, got %d", len(Repositories), len(pvcs))
+	}
+
+	for i, repository := range Repositories {
+		pvc := pvcs[i]
+		if pvc.Name != RepositoryVolumeName(repository) {
+			t.Errorf("expected PVC name %q, got %q", RepositoryVolumeName(repository), pvc.Name)
+		}
+		expected := defaultRepositoryCapacity[repository]
+		got := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		if got.Cmp(expected) != 0 {
+			t.Errorf("%s: expected default capacity %s, got %s", repository, expected.String(), got.String())
+		}
+		if pvc.Spec.StorageClassName != nil {
+			t.Errorf("%s: expected no storage class, got %q", repository, *pvc.Spec.StorageClassName)
+		}
+	}
+}
+
+func TestGetRepositoryVolumeClaimTemplates_Override(t *testing.T) {
+	capacity := resource.MustParse("50Gi")
+	storage := &nifiv1alpha1.StorageSpec{
+		ContentRepo: &nifiv1alpha1.RepositoryStorageSpec{
+			Capacity:     &capacity,
+			StorageClass: "fast-ssd",
+		},
+		// Only a storage class, the capacity keeps its default.
+		ProvenanceRepo: &nifiv1alpha1.RepositoryStorageSpec{
+			StorageClass: "standard",
+		},
+	}
+
+	pvcs, err := getRepositoryVolumeClaimTemplates(storage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	byName := make(map[string]corev1.PersistentVolumeClaim, len(pvcs))
+	for _, pvc := range pvcs {
+		byName[pvc.Name] = pvc
+	}
+
+	content := byName[RepositoryVolumeName("content")]
+	if got := content.Spec.Resources.Requests[corev1.ResourceStorage]; got.Cmp(capacity) != 0 {
+		t.Errorf("expected content capacity %s, got %s", capacity.String(), got.String())
+	}
+	if content.Spec.StorageClassName == nil || *content.Spec.StorageClassName != "fast-ssd" {
+		t.Errorf("expected content storage class fast-ssd, got %v", content.Spec.StorageClassName)
+	}
+
+	provenance := byName[RepositoryVolumeName("provenance")]
+	expected := defaultRepositoryCapacity["provenance"]
+	if got := provenance.Spec.Resources.Requests[corev1.ResourceStorage]; got.Cmp(expected) != 0 {
+		t.Errorf("expected provenance capacity %s, got %s", expected.String(), got.String())
+	}
+	if provenance.Spec.StorageClassName == nil || *provenance.Spec.StorageClassName != "standard" {
+		t.Errorf("expected provenance storage class standard, got %v", provenance.Spec.StorageClassName)
+	}
+}
+
+func TestGetRepositoryVolumeMounts(t *testing.T) {
+	mounts, err := getRepositoryVolumeMounts(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, mount := range mounts {
+		found := false
+		for _, repository := range Repositories {
+			if mount.Name == RepositoryVolumeName(repository) {
+				found = true
+				if mount.MountPath != NifiRepositoryMouhtPath[repository] {
+					t.Errorf("%s: expected mount path %q, got %q", repository, NifiRepositoryMouhtPath[repository], mount.MountPath)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("unexpected volume mount %q", mount.Name)
+		}
+	}
+}
+
+func TestGetRepositoryVolumes_Directories(t *testing.T) {
+	capacity := resource.MustParse("100Gi")
+	storage := &nifiv1alpha1.StorageSpec{
+		ContentRepoVolumes: map[string]nifiv1alpha1.RepositoryStorageSpec{
+			"disk2": {Capacity: &capacity, StorageClass: "local"},
+			"disk1": {},
+		},
+		ProvenanceRepoVolumes: map[string]nifiv1alpha1.RepositoryStorageSpec{
+			"disk1": {},
+		},
+	}
+
+	volumes, err := getRepositoryVolumes(storage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(volumes) != len(Repositories)+3 {
+		t.Fatalf("expected %d volumes, got %d", len(Repositories)+3, len(volumes))
+	}
+
+	extra := volumes[len(Repositories):]
+	expectedNames := []string{
+		"content-repository-disk1",
+		"content-repository-disk2",
+		"provenance-repository-disk1",
+	}
+	for i, name := range expectedNames {
+		if extra[i].Name != name {
+			t.Errorf("expected volume %d to be %q, got %q", i, name, extra[i].Name)
+		}
+		if extra[i].MountPath != "/kubedoop/data/"+name {
+			t.Errorf("expected mount path /kubedoop/data/%s, got %q", name, extra[i].MountPath)
+		}
+	}
+
+	if extra[0].Capacity.Cmp(defaultRepositoryCapacity["content"]) != 0 {
+		t.Errorf("expected default content capacity, got %s", extra[0].Capacity.String())
+	}
+	if extra[1].Capacity.Cmp(capacity) != 0 || extra[1].StorageClass != "local" {
+		t.Errorf("unexpected content disk2 volume: %+v", extra[1])
+	}
+	if extra[2].Capacity.Cmp(defaultRepositoryCapacity["provenance"]) != 0 {
+		t.Errorf("expected default provenance capacity, got %s", extra[2].Capacity.String())
+	}
+}
+
+func TestGetRepositoryVolumes_InvalidDirectoryName(t *testing.T) {
+	for _, name := range []string{"default", "Disk_1", ""} {
+		storage := &nifiv1alpha1.StorageSpec{
+			ContentRepoVolumes: map[string]nifiv1alpha1.RepositoryStorageSpec{name: {}},
+		}
+		if _, err := getRepositoryVolumes(storage); err == nil {
+			t.Errorf("expected an error for content repository volume name %q", name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/zncdatadev/operator-go/pkg/builder"
+	"github.com/zncdatadev/operator-go/pkg/constants"
+	"github.com/zncdatadev/operator-go/pkg/productlogging"
+	corev1 "k8s.io/api/core/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+// vectorAggregatorAddressKey is the key of the aggregator address in the vector aggregator discovery ConfigMap.
+const vectorAggregatorAddressKey = "ADDRESS"
+
+var (
+	// NifiLogDir is the log directory of the NiFi installation, it is the `nifi` directory of the shared
+	// log volume, so the files NiFi writes there outside of logback do not fill the container filesystem.
+	NifiLogDir = path.Join(NifiRoot, "logs")
+)
+
+const nifiLogVolumeSubPath = "nifi"
+
+// IsVectorEnabled reports whether the Vector agent is enabled by `config.logging.enableVectorAgent`.
+func IsVectorEnabled(config *nifiv1alpha1.ConfigSpec) bool {
+	return config != nil && config.RoleGroupConfigSpec != nil && config.Logging != nil &&
+		config.Logging.EnableVectorAgent != nil && *config.Logging.EnableVectorAgent
+}
+
+// getLogVolumeMounts mounts the shared log volume, logback writes to the directory of the container, see
+// getLogbackConfig, and the shutdown file of the Vector agent is created in its `_vector` directory.
+func getLogVolumeMounts() []corev1.VolumeMount {
+	return []corev1.VolumeMount{
+		{
+			Name:      builder.LogDataVolumeName,
+			MountPath: constants.KubedoopLogDir,
+		},
+		{
+			Name:      builder.LogDataVolumeName,
+			MountPath: NifiLogDir,
+			SubPath:   nifiLogVolumeSubPath,
+		},
+	}
+}
+
+// checkVectorAggregatorConfigMap checks the discovery ConfigMap of the Vector aggregator, the shared vector.yaml
+// generator silently renders an empty address without it.
+func checkVectorAggregatorConfigMap(ctx context.Context, client ctrlclient.Client, namespace string, configMapName string) error {
+	if configMapName == "" {
+		return fmt.Errorf("logging.enableVectorAgent requires clusterConfig.vectorAggregatorConfigMapName")
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := client.Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: configMapName}, cm); err != nil {
+		return fmt.Errorf("failed to get vector aggregator ConfigMap %s: %w", configMapName, err)
+	}
+
+	if cm.Data[vectorAggregatorAddressKey] == "" {
+		return fmt.Errorf("vector aggregator ConfigMap %s does not contain %s", configMapName, vectorAggregatorAddressKey)
+	}
+	return nil
+}
+
+// getVectorConfig returns vector.yaml, the config of the Vector agent shared by the products of the platform.
+// It ships the nifi.log4j.xml file written by logback to the aggregator.
+func getVectorConfig(ctx context.Context, client ctrlclient.Client, namespace, cluster, roleName, roleGroupName, configMapName string) (string, error) {
+	if err := checkVectorAggregatorConfigMap(ctx, client, namespace, configMapName); err != nil {
+		return "", err
+	}
+	return productlogging.MakeVectorYaml(ctx, client, namespace, cluster, roleName, roleGroupName, configMapName)
+}
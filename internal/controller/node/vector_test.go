@@ -0,0 +1,89 @@
+package node
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func TestIsVectorEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *nifiv1alpha1.ConfigSpec
+		expected bool
+	}{
+		{name: "nil config", config: nil, expected: false},
+		{name: "no logging", config: &nifiv1alpha1.ConfigSpec{RoleGroupConfigSpec: &commonsv1alpha1.RoleGroupConfigSpec{}}, expected: false},
+		{
+			name: "disabled",
+			config: &nifiv1alpha1.ConfigSpec{RoleGroupConfigSpec: &commonsv1alpha1.RoleGroupConfigSpec{
+				Logging: &commonsv1alpha1.LoggingSpec{EnableVectorAgent: ptr.To(false)},
+			}},
+			expected: false,
+		},
+		{
+			name: "enabled",
+			config: &nifiv1alpha1.ConfigSpec{RoleGroupConfigSpec: &commonsv1alpha1.RoleGroupConfigSpec{
+				Logging: &commonsv1alpha1.LoggingSpec{EnableVectorAgent: ptr.To(true)},
+			}},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsVectorEnabled(tt.config); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetVectorConfig(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "vector-aggregator", Namespace: "default"},
+		Data:       map[string]string{vectorAggregatorAddressKey: "vector-aggregator:6000"},
+	}).Build()
+
+	got, err := getVectorConfig(ctx, c, "default", "simple-nifi", "node", "default", "vector-aggregator")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		// The shared config ships the file of the XML layout logback writes, see getLogbackConfig.
+		"- /kubedoop/log/*/*.log4j.xml",
+		`.cluster = "simple-nifi"`,
+		`.roleGroup = "default"`,
+		`address: "vector-aggregator:6000"`,
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+
+	if _, err := getVectorConfig(ctx, c, "default", "simple-nifi", "node", "default", "missing"); err == nil {
+		t.Error("expected an error when the vector aggregator ConfigMap does not exist")
+	}
+}
+
+func TestGetVectorConfig_MissingConfigMapName(t *testing.T) {
+	if _, err := getVectorConfig(context.Background(), nil, "default", "simple-nifi", "node", "default", ""); err == nil {
+		t.Error("expected an error when vectorAggregatorConfigMapName is not set")
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2025 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+	"github.com/zncdatadev/nifi-operator/internal/common"
+	"github.com/zncdatadev/nifi-operator/internal/common/security"
+	"github.com/zncdatadev/nifi-operator/internal/controller/node"
+)
+
+// nifiClusterLog is for logging in this package.
+var nifiClusterLog = logf.Log.WithName("nificluster-resource")
+
+// nodeRoleName is the role name used in the names of the node resources, see cluster.RegisterResources.
+const nodeRoleName = "node"
+
+// maxStatefulSetNameLength keeps room in the 63 character label limit for the
+// `controller-revision-hash` label the StatefulSet controller adds to its pods.
+const maxStatefulSetNameLength = 52
+
+// SetupNifiClusterWebhookWithManager registers the webhook for NifiCluster in the manager.
+func SetupNifiClusterWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &nifiv1alpha1.NifiCluster{}).
+		WithValidator(&NifiClusterCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-nifi-kubedoop-dev-v1alpha1-nificluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=nifi.kubedoop.dev,resources=nificlusters,verbs=create;update,versions=v1alpha1,name=vnificluster-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// NifiClusterCustomValidator rejects NifiCluster specs the operator is not able to roll out.
+type NifiClusterCustomValidator struct{}
+
+var _ admission.Validator[*nifiv1alpha1.NifiCluster] = &NifiClusterCustomValidator{}
+
+// ValidateCreate implements admission.Validator so a webhook will be registered for the type NifiCluster.
+func (v *NifiClusterCustomValidator) ValidateCreate(_ context.Context, obj *nifiv1alpha1.NifiCluster) (admission.Warnings, error) {
+	nifiClusterLog.Info("Validation for NifiCluster upon creation", "name", obj.GetName())
+
+	return nil, validateNifiCluster(obj)
+}
+
+// ValidateUpdate implements admission.Validator so a webhook will be registered for the type NifiCluster.
+func (v *NifiClusterCustomValidator) ValidateUpdate(_ context.Context, _, newObj *nifiv1alpha1.NifiCluster) (admission.Warnings, error) {
+	nifiClusterLog.Info("Validation for NifiCluster upon update", "name", newObj.GetName())
+
+	return nil, validateNifiCluster(newObj)
+}
+
+// ValidateDelete implements admission.Validator so a webhook will be registered for the type NifiCluster.
+func (v *NifiClusterCustomValidator) ValidateDelete(_ context.Context, _ *nifiv1alpha1.NifiCluster) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateNifiCluster(obj *nifiv1alpha1.NifiCluster) error {
+	specPath := field.NewPath("spec")
+
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, validateClusterConfig(obj.Spec.ClusterConfig, productVersion(&obj.Spec), security.GetNodeCount(obj.Spec.Nodes), specPath.Child("clusterConfig"))...)
+	allErrs = append(allErrs, validateRoleGroups(obj.Name, obj.Spec.Nodes, specPath.Child("nodes"))...)
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(nifiv1alpha1.GroupVersion.WithKind("NifiCluster").GroupKind(), obj.Name, allErrs)
+}
+
+// productVersion returns the NiFi version the cluster runs, see cluster.GetImage.
+func productVersion(spec *nifiv1alpha1.NifiClusterSpec) string {
+	if spec.Image != nil && spec.Image.ProductVersion != "" {
+		return spec.Image.ProductVersion
+	}
+	return nifiv1alpha1.DefaultProductVersion
+}
+
+// validateClusterConfig validates the cluster config of a cluster of the given product version and number of nodes.
+func validateClusterConfig(config *nifiv1alpha1.ClusterConfigSpec, version string, nodes int32, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if config == nil {
+		return append(allErrs, field.Required(path, "clusterConfig is required"))
+	}
+
+	authPath := path.Child("authentication")
+	authClasses := make(map[string]struct{}, len(config.Authentication))
+	for i, auth := range config.Authentication {
+		if auth.AuthenticationClass == "" {
+			allErrs = append(allErrs, field.Required(authPath.Index(i).Child("authenticationClass"), "authentication class is required"))
+			continue
+		}
+		// The combinations of authentication providers are checked when the AuthenticationClasses are resolved.
+		if _, exists := authClasses[auth.AuthenticationClass]; exists {
+			allErrs = append(allErrs, field.Duplicate(authPath.Index(i).Child("authenticationClass"), auth.AuthenticationClass))
+		}
+		authClasses[auth.AuthenticationClass] = struct{}{}
+		if auth.LdapGroups != nil {
+			if _, err := security.GetLdapSyncInterval(auth.LdapGroups); err != nil {
+				allErrs = append(allErrs, field.Invalid(authPath.Index(i).Child("ldapGroups", "syncInterval"), auth.LdapGroups.SyncInterval, err.Error()))
+			}
+		}
+	}
+
+	if config.Authorization != nil && config.Authorization.Opa != nil {
+		opaPath := path.Child("authorization", "opa")
+		// NiFi only authorizes the requests of authenticated users.
+		if len(config.Authentication) == 0 {
+			allErrs = append(allErrs, field.Required(authPath, "OPA authorization requires authentication"))
+		}
+		if config.Authorization.Opa.ConfigMapName == "" {
+			allErrs = append(allErrs, field.Required(opaPath.Child("configMapName"), "the OPA discovery ConfigMap is required"))
+		}
+		if _, _, err := security.GetOpaCacheConfig(config.Authorization.Opa); err != nil {
+			allErrs = append(allErrs, field.Invalid(opaPath.Child("cache", "entryTimeToLive"), config.Authorization.Opa.Cache.EntryTimeToLive, err.Error()))
+		}
+		// OPA decides on its own policies, the policy sets of the OIDC groups are not seeded.
+		for i, auth := range config.Authentication {
+			if auth.OidcGroups != nil && len(auth.OidcGroups.Admin)+len(auth.OidcGroups.Operator)+len(auth.OidcGroups.ReadOnly) > 0 {
+				allErrs = append(allErrs, field.Forbidden(authPath.Index(i).Child("oidcGroups"), "the policies of the OIDC groups are not used with OPA authorization"))
+			}
+		}
+	}
+
+	if _, err := security.GetCertLifetime(config.Tls, nodes); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("tls", "certLifetime"), config.Tls.CertLifetime, err.Error()))
+	}
+
+	if _, err := common.DecodeExtraVolumes(config.ExtraVolumes); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("extraVolumes"), string(config.ExtraVolumes.Raw), err.Error()))
+	}
+
+	// NiFi 1.x does not ship the Kubernetes state provider and leader election manager.
+	if strings.HasPrefix(version, "1.") && (config.ZookeeperConfigMapName == nil || *config.ZookeeperConfigMapName == "") {
+		allErrs = append(allErrs, field.Required(
+			path.Child("zookeeperConfigMapName"),
+			fmt.Sprintf("NiFi %s does not support Kubernetes-native clustering, a ZooKeeper connection is required", version),
+		))
+	}
+
+	return allErrs
+}
+
+func validateRoleGroups(clusterName string, nodes *nifiv1alpha1.NodesSpec, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if nodes == nil {
+		return allErrs
+	}
+
+	allErrs = append(allErrs, validateStorage(nodes.Config, path.Child("config", "storage"))...)
+
+	roleGroupsPath := path.Child("roleGroups")
+	for _, name := range slices.Sorted(maps.Keys(nodes.RoleGroups)) {
+		for _, msg := range validation.IsDNS1123Label(name) {
+			allErrs = append(allErrs, field.Invalid(roleGroupsPath.Key(name), name, msg))
+		}
+		allErrs = append(allErrs, validateStorage(nodes.RoleGroups[name].Config, roleGroupsPath.Key(name).Child("config", "storage"))...)
+
+		// The StatefulSet and its headless Service share this name, pods are addressed as
+		// `<name>-<ordinal>.<name>.<namespace>.svc.cluster.local`.
+		resourceName := fmt.Sprintf("%s-%s-%s", clusterName, nodeRoleName, name)
+		if msgs := validation.IsDNS1035Label(resourceName); len(msgs) > 0 {
+			for _, msg := range msgs {
+				allErrs = append(allErrs, field.Invalid(roleGroupsPath.Key(name), resourceName, msg))
+			}
+		} else if len(resourceName) > maxStatefulSetNameLength {
+			allErrs = append(allErrs, field.Invalid(
+				roleGroupsPath.Key(name),
+				resourceName,
+				fmt.Sprintf("must be no more than %d characters, shorten the cluster or role group name", maxStatefulSetNameLength),
+			))
+		}
+	}
+
+	return allErrs
+}
+
+// validateStorage checks the names of the additional content and provenance repository directories,
+// they end up in the names of the PersistentVolumeClaims.
+func validateStorage(config *nifiv1alpha1.ConfigSpec, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if config == nil || config.Storage == nil {
+		return allErrs
+	}
+
+	for _, repository := range []struct {
+		name        string
+		directories map[string]nifiv1alpha1.RepositoryStorageSpec
+	}{
+		{"content", config.Storage.ContentRepoVolumes},
+		{"provenance", config.Storage.ProvenanceRepoVolumes},
+	} {
+		for _, name := range slices.Sorted(maps.Keys(repository.directories)) {
+			if err := node.ValidateRepositoryDirectoryName(repository.name, name); err != nil {
+				allErrs = append(allErrs, field.Invalid(path.Child(repository.name+"RepoVolumes").Key(name), name, err.Error()))
+			}
+		}
+	}
+	return allErrs
+}
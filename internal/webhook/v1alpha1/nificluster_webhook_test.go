@@ -0,0 +1,234 @@
+/*
+Copyright 2025 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	nifiv1alpha1 "github.com/zncdatadev/nifi-operator/api/v1alpha1"
+)
+
+func newNifiCluster(name string) *nifiv1alpha1.NifiCluster {
+	return &nifiv1alpha1.NifiCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: nifiv1alpha1.NifiClusterSpec{
+			ClusterConfig: &nifiv1alpha1.ClusterConfigSpec{
+				SensitiveProperties: &nifiv1alpha1.SensitivePropertiesSpec{
+					KeySecret:    "nifi-sensitive-property-key",
+					AutoGenerate: true,
+				},
+			},
+			Nodes: &nifiv1alpha1.NodesSpec{
+				RoleGroups: map[string]nifiv1alpha1.RoleGroupSpec{
+					"default": {Replicas: ptr.To[int32](1)},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("NifiCluster Webhook", func() {
+	var (
+		obj       *nifiv1alpha1.NifiCluster
+		validator NifiClusterCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = newNifiCluster("simple-nifi")
+		validator = NifiClusterCustomValidator{}
+	})
+
+	Context("When creating or updating NifiCluster under Validating Webhook", func() {
+		It("Should admit a valid spec", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should admit several authentication classes", func() {
+			obj.Spec.ClusterConfig.Authentication = []nifiv1alpha1.AuthenticationSpec{
+				{AuthenticationClass: "ldap"},
+				{AuthenticationClass: "tls"},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny an authentication class referenced twice", func() {
+			obj.Spec.ClusterConfig.Authentication = []nifiv1alpha1.AuthenticationSpec{
+				{AuthenticationClass: "ldap"},
+				{AuthenticationClass: "ldap"},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.clusterConfig.authentication[1].authenticationClass"))
+		})
+
+		It("Should deny an authentication spec without authentication class", func() {
+			obj.Spec.ClusterConfig.Authentication = []nifiv1alpha1.AuthenticationSpec{{}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.clusterConfig.authentication[0].authenticationClass"))
+		})
+
+		It("Should deny NiFi 1.x without ZooKeeper", func() {
+			obj.Spec.Image = &nifiv1alpha1.ImageSpec{ProductVersion: "1.27.0"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.clusterConfig.zookeeperConfigMapName"))
+
+			By("admitting it once a ZooKeeper ConfigMap is set")
+			obj.Spec.ClusterConfig.ZookeeperConfigMapName = ptr.To("nifi-znode")
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny role group names which do not produce valid DNS names", func() {
+			obj.Spec.Nodes.RoleGroups["Invalid_Group"] = nifiv1alpha1.RoleGroupSpec{}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.nodes.roleGroups[Invalid_Group]"))
+		})
+
+		It("Should deny role groups whose StatefulSet name is too long", func() {
+			obj.Spec.Nodes.RoleGroups[strings.Repeat("a", 40)] = nifiv1alpha1.RoleGroupSpec{}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("must be no more than 52 characters"))
+		})
+
+		It("Should deny repository volume names which do not produce valid PVC names", func() {
+			obj.Spec.Nodes.RoleGroups["default"] = nifiv1alpha1.RoleGroupSpec{Config: &nifiv1alpha1.ConfigSpec{
+				Storage: &nifiv1alpha1.StorageSpec{
+					ContentRepoVolumes: map[string]nifiv1alpha1.RepositoryStorageSpec{"default": {}},
+				},
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.nodes.roleGroups[default].config.storage.contentRepoVolumes[default]"))
+
+			By("admitting a DNS label")
+			obj.Spec.Nodes.RoleGroups["default"].Config.Storage.ContentRepoVolumes = map[string]nifiv1alpha1.RepositoryStorageSpec{"disk1": {}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny extra volumes which are not a list of volumes", func() {
+			obj.Spec.ClusterConfig.ExtraVolumes = &runtime.RawExtension{Raw: []byte(`{"name": "keytab"}`)}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.clusterConfig.extraVolumes"))
+
+			By("admitting a list of volumes")
+			obj.Spec.ClusterConfig.ExtraVolumes = &runtime.RawExtension{Raw: []byte(`[{"name": "keytab", "secret": {"secretName": "nifi-keytab"}}]`)}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny OPA authorization without authentication", func() {
+			obj.Spec.ClusterConfig.Authorization = &nifiv1alpha1.AuthorizationSpec{
+				Opa: &nifiv1alpha1.OpaSpec{ConfigMapName: "opa", Cache: &nifiv1alpha1.OpaCacheSpec{EntryTimeToLive: "soon"}},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("OPA authorization requires authentication"))
+			Expect(err.Error()).To(ContainSubstring("spec.clusterConfig.authorization.opa.cache.entryTimeToLive"))
+
+			By("admitting OPA authorization of authenticated users")
+			obj.Spec.ClusterConfig.Authentication = []nifiv1alpha1.AuthenticationSpec{{AuthenticationClass: "ldap"}}
+			obj.Spec.ClusterConfig.Authorization.Opa.Cache.EntryTimeToLive = "1m"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny an invalid LDAP sync interval", func() {
+			obj.Spec.ClusterConfig.Authentication = []nifiv1alpha1.AuthenticationSpec{{
+				AuthenticationClass: "ldap",
+				LdapGroups:          &nifiv1alpha1.LdapGroupsSpec{SyncInterval: "30 mins"},
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.clusterConfig.authentication[0].ldapGroups.syncInterval"))
+
+			By("admitting a go duration")
+			obj.Spec.ClusterConfig.Authentication[0].LdapGroups.SyncInterval = "30m"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny OIDC group policies with OPA authorization", func() {
+			obj.Spec.ClusterConfig.Authentication = []nifiv1alpha1.AuthenticationSpec{{
+				AuthenticationClass: "keycloak",
+				OidcGroups:          &nifiv1alpha1.OidcGroupsSpec{Admin: []string{"nifi-admins"}},
+			}}
+			obj.Spec.ClusterConfig.Authorization = &nifiv1alpha1.AuthorizationSpec{Opa: &nifiv1alpha1.OpaSpec{ConfigMapName: "opa"}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.clusterConfig.authentication[0].oidcGroups"))
+
+			By("admitting OIDC group policies managed in NiFi")
+			obj.Spec.ClusterConfig.Authorization = nil
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should validate the TLS certificate lifetime", func() {
+			obj.Spec.ClusterConfig.Tls = &nifiv1alpha1.TlsSpec{ServerSecretClass: "tls", CertLifetime: "10m"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.clusterConfig.tls.certLifetime"))
+
+			By("admitting a lifetime leaving time to restart the nodes")
+			obj.Spec.ClusterConfig.Tls.CertLifetime = "1h"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			By("rejecting a lifetime too short to restart every node one at a time")
+			obj.Spec.Nodes.RoleGroups["default"] = nifiv1alpha1.RoleGroupSpec{Replicas: ptr.To[int32](5)}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.clusterConfig.tls.certLifetime"))
+
+			obj.Spec.ClusterConfig.Tls.CertLifetime = "24h"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should validate updates", func() {
+			newObj := obj.DeepCopy()
+			newObj.Spec.Image = &nifiv1alpha1.ImageSpec{ProductVersion: "1.27.0"}
+			Expect(validator.ValidateUpdate(ctx, obj, newObj)).Error().To(HaveOccurred())
+		})
+	})
+
+	Context("When the webhook is installed in the API server", func() {
+		It("Should reject an invalid NifiCluster", func() {
+			invalid := newNifiCluster("invalid-nifi")
+			invalid.Spec.Image = &nifiv1alpha1.ImageSpec{ProductVersion: "1.27.0"}
+
+			err := k8sClient.Create(ctx, invalid)
+			Expect(err).To(HaveOccurred())
+			Expect(apierrors.IsForbidden(err) || apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("ZooKeeper"))
+		})
+
+		It("Should admit a valid NifiCluster", func() {
+			valid := newNifiCluster("valid-nifi")
+			Expect(k8sClient.Create(ctx, valid)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, valid)).To(Succeed())
+		})
+	})
+})